@@ -0,0 +1,137 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package bootstrap
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/corelayer/clapp/pkg/clapp"
+	"github.com/spf13/cobra"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+// SetLogger configures the default logger with the level from the loglevel flag
+func SetLogger(cmd *cobra.Command) error {
+	var (
+		err          error
+		logLevelFlag string
+		level        slog.Leveler
+	)
+
+	logLevelFlag, err = cmd.Flags().GetString("loglevel")
+	if err != nil {
+		slog.Error("could not find flag", "flag", "loglevel")
+		return err
+	}
+
+	switch logLevelFlag {
+	case "error":
+		level = slog.LevelError
+	case "warn":
+		level = slog.LevelWarn
+	case "info":
+		level = slog.LevelInfo
+	case "debug":
+		level = slog.LevelDebug
+	default:
+		level = slog.LevelInfo
+	}
+
+	// logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+	slog.SetDefault(logger)
+	return nil
+}
+
+// LoadApplication reads the application configuration and environment file set by the persistent flags of the command
+// Included files are merged and environment variables are replaced in the returned configuration
+func LoadApplication(cmd *cobra.Command) (config.Application, error) {
+	var (
+		err        error
+		configFile string
+		envFile    string
+		path       string
+		search     []string
+		appConfig  config.Application
+	)
+
+	configFile, err = cmd.Flags().GetString("configFile")
+	if err != nil {
+		slog.Error("could not find flag", "flag", "configFile")
+		return config.Application{}, err
+	}
+
+	envFile, err = cmd.Flags().GetString("envFile")
+	if err != nil {
+		slog.Error("could not find flag", "flag", "envFile")
+		return config.Application{}, err
+	}
+
+	path, err = cmd.Flags().GetString("path")
+	if err != nil {
+		slog.Error("could not find flag", "flag", "path")
+		return config.Application{}, err
+	}
+
+	search, err = cmd.Flags().GetStringSlice("search")
+	if err != nil {
+		slog.Error("could not find flag", "flag", "search")
+		return config.Application{}, err
+	}
+
+	// Setup application environment variables
+	appEnvFile := clapp.NewConfiguration(envFile, path, search)
+	viperEnv := appEnvFile.GetViper()
+	viperEnv.SetEnvPrefix("lens")
+	viperEnv.AutomaticEnv()
+	err = viperEnv.ReadInConfig()
+	if err != nil {
+		slog.Error("could not read configuration", "file", viperEnv.ConfigFileUsed(), "error", err)
+		return config.Application{}, err
+	}
+
+	// Setup application configuration
+	appConfigFile := clapp.NewConfiguration(configFile, path, search)
+	viperFile := appConfigFile.GetViper()
+
+	err = viperFile.ReadInConfig()
+	if err != nil {
+		slog.Error("could not read configuration", "error", err)
+		return config.Application{}, err
+	}
+
+	err = viperFile.Unmarshal(&appConfig)
+	if err != nil {
+		slog.Error("could not unmarshal configuration", "error", err)
+		return config.Application{}, err
+	}
+
+	err = appConfig.MergeIncludes(viperFile.ConfigFileUsed())
+	if err != nil {
+		slog.Error("could not merge included configuration", "error", err)
+		return config.Application{}, err
+	}
+
+	err = appConfig.UpdateEnvironmentVariables(viperEnv)
+	if err != nil {
+		slog.Error("could not update environment variables in config", "error", err)
+		return config.Application{}, err
+	}
+	return appConfig, nil
+}
@@ -17,10 +17,15 @@
 package certificate
 
 import (
+	"log/slog"
+	"os"
+
 	"github.com/corelayer/clapp/pkg/clapp"
 	"github.com/spf13/cobra"
 
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/bootstrap"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers/command"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
 )
 
 var Command = clapp.Command{
@@ -31,14 +36,38 @@ var Command = clapp.Command{
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var example bool
+			var output string
 			var c clapp.CommandController
 			example, err = cmd.Flags().GetBool("example")
+			if err != nil {
+				return err
+			}
+
+			output, err = cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+
 			if example {
-				c = command.ConfigureCertificateExample{}
-			} else {
-				c = command.ConfigureCertificate{}
+				c = command.ConfigureCertificateExample{
+					Output: output,
+				}
+				return c.Execute()
 			}
 
+			// Only log warnings and errors, to keep the questions of the wizard readable
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+			slog.SetDefault(logger)
+
+			var appConfig config.Application
+			appConfig, err = bootstrap.LoadApplication(cmd)
+			if err != nil {
+				return err
+			}
+
+			c = command.ConfigureCertificate{
+				Config: appConfig,
+			}
 			err = c.Execute()
 			return err
 		},
@@ -48,5 +77,6 @@ var Command = clapp.Command{
 }
 
 func init() {
-	Command.Cobra.Flags().BoolP("example", "e", false, "generate example")
+	Command.Cobra.Flags().Bool("example", false, "generate example")
+	Command.Cobra.Flags().StringP("output", "o", "", "example output file, defaults to stdout")
 }
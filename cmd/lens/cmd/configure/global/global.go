@@ -31,10 +31,22 @@ var Command = clapp.Command{
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var err error
 			var example bool
+			var output string
 			var c clapp.CommandController
 			example, err = cmd.Flags().GetBool("example")
+			if err != nil {
+				return err
+			}
+
+			output, err = cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+
 			if example {
-				c = command.ConfigureGlobalExample{}
+				c = command.ConfigureGlobalExample{
+					Output: output,
+				}
 			} else {
 				c = command.ConfigureGlobal{}
 			}
@@ -48,5 +60,6 @@ var Command = clapp.Command{
 }
 
 func init() {
-	Command.Cobra.Flags().BoolP("example", "e", false, "generate example")
+	Command.Cobra.Flags().Bool("example", false, "generate example")
+	Command.Cobra.Flags().StringP("output", "o", "", "example output file, defaults to stdout")
 }
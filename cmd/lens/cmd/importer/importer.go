@@ -0,0 +1,150 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package importer
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/corelayer/clapp/pkg/clapp"
+	"github.com/spf13/cobra"
+
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/bootstrap"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers/command"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/global"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/netscaleradc"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+var Command = clapp.Command{
+	Cobra: &cobra.Command{
+		Use:   "import",
+		Short: "Import mode",
+		Long:  global.LENS_BANNER + "\n\n" + global.LENS_TITLE + " - Import Mode",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// Get flag values from command
+			var target string
+			var user string
+			var service string
+			var challengeType string
+			var provider string
+			var output string
+			var takeover bool
+
+			target, err = cmd.Flags().GetString("target")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "target")
+				return err
+			}
+
+			user, err = cmd.Flags().GetString("user")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "user")
+				return err
+			}
+
+			service, err = cmd.Flags().GetString("service")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "service")
+				return err
+			}
+
+			challengeType, err = cmd.Flags().GetString("type")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "type")
+				return err
+			}
+
+			provider, err = cmd.Flags().GetString("provider")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "provider")
+				return err
+			}
+
+			output, err = cmd.Flags().GetString("output")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "output")
+				return err
+			}
+
+			takeover, err = cmd.Flags().GetBool("takeover")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "takeover")
+				return err
+			}
+
+			// The partition is optional, certificates are imported from the default partition if omitted
+			parts := strings.Split(target, "/")
+			if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+				slog.Error("invalid target, expected <organization>/<environment>[/<partition>]", "target", target)
+				return fmt.Errorf("invalid target %s, expected <organization>/<environment>[/<partition>]", target)
+			}
+			var partition string
+			if len(parts) == 3 {
+				partition = parts[2]
+			}
+
+			err = bootstrap.SetLogger(cmd)
+			if err != nil {
+				return err
+			}
+
+			var appConfig config.Application
+			appConfig, err = bootstrap.LoadApplication(cmd)
+			if err != nil {
+				return err
+			}
+
+			c := command.Import{
+				Config: appConfig,
+				Target: config.Target{
+					Organization: parts[0],
+					Environment:  parts[1],
+					Partition:    partition,
+				},
+				User: user,
+				Challenge: config.Challenge{
+					Service:  service,
+					Type:     challengeType,
+					Provider: provider,
+				},
+				Output:   output,
+				Takeover: takeover,
+			}
+			err = c.Execute()
+			return err
+		},
+		SilenceErrors: true,
+		SilenceUsage:  false,
+	},
+}
+
+func init() {
+	Command.Cobra.Flags().StringP("target", "t", "", "target to import certificates from: <organization>/<environment>[/<partition>]")
+	Command.Cobra.Flags().StringP("user", "u", "", "user for the certificate requests")
+	Command.Cobra.Flags().String("service", config.ACME_SERVICE_LETSENCRYPT_PRODUCTION, "acme service for the certificate requests")
+	Command.Cobra.Flags().String("type", config.ACME_CHALLENGE_TYPE_HTTP, "challenge type for the certificate requests")
+	Command.Cobra.Flags().String("provider", netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, "challenge provider for the certificate requests")
+	Command.Cobra.Flags().StringP("output", "o", "", "output directory for the certificate configurations, defaults to configPath")
+	Command.Cobra.Flags().Bool("takeover", false, "keep the existing certkey names instead of installing new LENS_<name> certkeys")
+
+	Command.Cobra.MarkFlagRequired("target")
+	Command.Cobra.MarkFlagRequired("user")
+}
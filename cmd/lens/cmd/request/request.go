@@ -18,11 +18,11 @@ package request
 
 import (
 	"log/slog"
-	"os"
 
 	"github.com/corelayer/clapp/pkg/clapp"
 	"github.com/spf13/cobra"
 
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/bootstrap"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers/command"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/global"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
@@ -37,36 +37,9 @@ var Command = clapp.Command{
 			var err error
 
 			// Get flag values from command
-			var configFile string
-			var envFile string
-			var path string
-			var search []string
 			var name string
 			var all bool
-
-			configFile, err = cmd.Flags().GetString("configFile")
-			if err != nil {
-				slog.Error("could not find flag", "flag", "configFile")
-				return err
-			}
-
-			envFile, err = cmd.Flags().GetString("envFile")
-			if err != nil {
-				slog.Error("could not find flag", "flag", "envFile")
-				return err
-			}
-
-			path, err = cmd.Flags().GetString("path")
-			if err != nil {
-				slog.Error("could not find flag", "flag", "path")
-				return err
-			}
-
-			search, err = cmd.Flags().GetStringSlice("search")
-			if err != nil {
-				slog.Error("could not find flag", "flag", "search")
-				return err
-			}
+			var force bool
 
 			name, err = cmd.Flags().GetString("name")
 			if err != nil {
@@ -80,62 +53,20 @@ var Command = clapp.Command{
 				return err
 			}
 
-			var logLevelFlag string
-			logLevelFlag, err = cmd.Flags().GetString("loglevel")
-			if err != nil {
-				slog.Error("could not find flag", "loglevel", "all")
-				return err
-			}
-
-			var level slog.Leveler
-			switch logLevelFlag {
-			case "error":
-				level = slog.LevelError
-			case "warn":
-				level = slog.LevelWarn
-			case "info":
-				level = slog.LevelInfo
-			case "debug":
-				level = slog.LevelDebug
-			default:
-				level = slog.LevelInfo
-			}
-
-			// logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
-			logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
-			slog.SetDefault(logger)
-
-			// Setup application environment variables
-			appEnvFile := clapp.NewConfiguration(envFile, path, search)
-			viperEnv := appEnvFile.GetViper()
-			viperEnv.SetEnvPrefix("lens")
-			viperEnv.AutomaticEnv()
-			err = viperEnv.ReadInConfig()
+			force, err = cmd.Flags().GetBool("force")
 			if err != nil {
-				slog.Error("could not read configuration", "file", viperEnv.ConfigFileUsed(), "error", err)
+				slog.Error("could not find flag", "flag", "force")
 				return err
 			}
 
-			// Setup application configuration
-			appConfigFile := clapp.NewConfiguration(configFile, path, search)
-			viperFile := appConfigFile.GetViper()
-
-			err = viperFile.ReadInConfig()
+			err = bootstrap.SetLogger(cmd)
 			if err != nil {
-				slog.Error("could not read configuration", "error", err)
 				return err
 			}
 
 			var appConfig config.Application
-			err = viperFile.Unmarshal(&appConfig)
-			if err != nil {
-				slog.Error("could not unmarshal configuration", "error", err)
-				return err
-			}
-
-			err = appConfig.UpdateEnvironmentVariables(viperEnv)
+			appConfig, err = bootstrap.LoadApplication(cmd)
 			if err != nil {
-				slog.Error("could not update environment variables in config", "error", err)
 				return err
 			}
 
@@ -145,6 +76,7 @@ var Command = clapp.Command{
 					Config:     appConfig,
 					Request:    name,
 					RequestAll: false,
+					Force:      force,
 				}
 			}
 
@@ -153,6 +85,7 @@ var Command = clapp.Command{
 					Config:     appConfig,
 					Request:    name,
 					RequestAll: all,
+					Force:      force,
 				}
 			}
 			err = c.Execute()
@@ -166,6 +99,7 @@ var Command = clapp.Command{
 func init() {
 	Command.Cobra.Flags().StringP("name", "n", "", "request name")
 	Command.Cobra.Flags().BoolP("all", "a", false, "request all")
+	Command.Cobra.Flags().BoolP("force", "f", false, "request certificates even if renewal is not due")
 
 	Command.Cobra.MarkFlagsMutuallyExclusive("name", "all")
 
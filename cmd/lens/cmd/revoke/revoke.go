@@ -0,0 +1,92 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package revoke
+
+import (
+	"log/slog"
+
+	"github.com/corelayer/clapp/pkg/clapp"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/spf13/cobra"
+
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/bootstrap"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers/command"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/global"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+var Command = clapp.Command{
+	Cobra: &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke mode",
+		Long:  global.LENS_BANNER + "\n\n" + global.LENS_TITLE + " - Revoke Mode",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// Get flag values from command
+			var name string
+			var reason uint
+			var remove bool
+
+			name, err = cmd.Flags().GetString("name")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "name")
+				return err
+			}
+
+			reason, err = cmd.Flags().GetUint("reason")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "reason")
+				return err
+			}
+
+			remove, err = cmd.Flags().GetBool("remove")
+			if err != nil {
+				slog.Error("could not find flag", "flag", "remove")
+				return err
+			}
+
+			err = bootstrap.SetLogger(cmd)
+			if err != nil {
+				return err
+			}
+
+			var appConfig config.Application
+			appConfig, err = bootstrap.LoadApplication(cmd)
+			if err != nil {
+				return err
+			}
+
+			c := command.Revoke{
+				Config: appConfig,
+				Name:   name,
+				Reason: reason,
+				Remove: remove,
+			}
+			err = c.Execute()
+			return err
+		},
+		SilenceErrors: true,
+		SilenceUsage:  false,
+	},
+}
+
+func init() {
+	Command.Cobra.Flags().StringP("name", "n", "", "certificate name")
+	Command.Cobra.Flags().UintP("reason", "r", acme.CRLReasonUnspecified, "revocation reason code (0: unspecified, 1: key compromise, 3: affiliation changed, 4: superseded, 5: cessation of operation)")
+	Command.Cobra.Flags().Bool("remove", false, "unbind and remove the certificate from the installation targets")
+}
@@ -22,7 +22,10 @@ import (
 
 	"github.com/corelayer/clapp/pkg/clapp"
 
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/configure"
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/importer"
 	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/request"
+	"github.com/corelayer/netscaleradc-acme-go/cmd/lens/cmd/revoke"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/global"
 )
 
@@ -74,8 +77,10 @@ func run() error {
 
 	app.RegisterCommands([]clapp.Commander{
 		// daemon.Command,
-		// configure.Command,
+		configure.Command,
+		importer.Command,
 		request.Command,
+		revoke.Command,
 	})
 
 	return app.Run()
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+const (
+	BINDING_ENDPOINT_VSERVER      = "vserver"
+	BINDING_ENDPOINT_SERVICE      = "service"
+	BINDING_ENDPOINT_SERVICEGROUP = "service group"
+	BINDING_ENDPOINT_PROFILE      = "ssl profile"
+)
+
+// BindingError reports a certkey which could not be bound to an endpoint on the target
+// Binding continues for the remaining endpoints, so an installation returns the binding errors of all failed endpoints joined together
+// using errors.Join, which can be unwrapped to inspect every failed binding.
+type BindingError struct {
+	Target      config.Target
+	CertKeyName string
+	Endpoint    string
+	Name        string
+	Err         error
+}
+
+func (e BindingError) Error() string {
+	return fmt.Sprintf("could not bind certificate %s to %s %s in organization %s environment %s with message %s", e.CertKeyName, e.Endpoint, e.Name, e.Target.Organization, e.Target.Environment, e.Err)
+}
+
+func (e BindingError) Unwrap() error {
+	return e.Err
+}
+
+// onlyBindingErrors reports whether err consists of binding errors only
+// Errors joined together are inspected recursively, a single error of another type makes the result false
+func onlyBindingErrors(err error) bool {
+	var (
+		joined interface{ Unwrap() []error }
+		ok     bool
+	)
+
+	if err == nil {
+		return false
+	}
+	if joined, ok = err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !onlyBindingErrors(e) {
+				return false
+			}
+		}
+		return len(joined.Unwrap()) > 0
+	}
+	_, ok = err.(BindingError)
+	return ok
+}
+
+// getFailedEndpoints returns the names of the endpoints of the given type in the binding errors of err
+// Errors joined together are inspected recursively
+func getFailedEndpoints(err error, endpoint string) map[string]bool {
+	var output = make(map[string]bool)
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			for name := range getFailedEndpoints(e, endpoint) {
+				output[name] = true
+			}
+		}
+	}
+	if bindingErr, ok := err.(BindingError); ok && bindingErr.Endpoint == endpoint {
+		output[bindingErr.Name] = true
+	}
+	return output
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOnlyBindingErrors(t *testing.T) {
+	bindingErr := BindingError{CertKeyName: "LENS_test", Endpoint: BINDING_ENDPOINT_VSERVER, Name: "VS_A", Err: errors.New("failed")}
+	otherErr := errors.New("could not get vserver bindings")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "binding error", err: bindingErr, want: true},
+		{name: "other error", err: otherErr, want: false},
+		{name: "joined binding errors", err: errors.Join(bindingErr, bindingErr), want: true},
+		{name: "binding error joined with other error", err: errors.Join(bindingErr, otherErr), want: false},
+		{name: "nested joined binding errors", err: errors.Join(errors.Join(bindingErr), errors.Join(bindingErr, bindingErr)), want: true},
+		{name: "nested other error", err: errors.Join(bindingErr, errors.Join(bindingErr, otherErr)), want: false},
+		{name: "wrapped binding error", err: fmt.Errorf("could not install certificate with message %w", bindingErr), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := onlyBindingErrors(tt.err); got != tt.want {
+				t.Errorf("onlyBindingErrors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ package command
 
 import (
 	"fmt"
+	"log/slog"
+	"os"
 )
 
 type Configure struct {
@@ -28,3 +30,20 @@ func (c *Configure) Execute() error {
 	fmt.Println("Configure settings")
 	return err
 }
+
+// writeExample writes data to the output file, or to stdout when no output file is specified
+func writeExample(output string, data []byte) error {
+	var err error
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err = os.WriteFile(output, data, 0o644); err != nil {
+		slog.Error("could not write example", "file", output, "error", err)
+		return fmt.Errorf("could not write example to %s with message %w", output, err)
+	}
+	slog.Info("example written", "file", output)
+	return nil
+}
@@ -18,13 +18,431 @@ package command
 
 import (
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro"
+	nitroConfig "github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/config"
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"gopkg.in/yaml.v3"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/netscaleradc"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/webserver"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
 )
 
+const (
+	ACME_CHALLENGE_PROVIDER_LEGO_DNS = "<lego dns provider>"
+	ACME_SERVICE_CUSTOM              = "<custom url>"
+)
+
+// ConfigureCertificate walks the operator through the creation of a certificate configuration file in the configPath
 type ConfigureCertificate struct {
+	Config config.Application
 }
 
 func (c ConfigureCertificate) Execute() error {
-	var err error
-	fmt.Println("Configure certificate settings")
-	return err
+	var (
+		err      error
+		cert     config.Certificate
+		filename string
+	)
+
+	p := newPrompter(os.Stdin, os.Stdout)
+	if cert, err = c.askCertificate(p); err != nil {
+		return err
+	}
+
+	if err = cert.Validate(); err != nil {
+		slog.Error("invalid certificate configuration", "certificate", cert.Name, "error", err)
+		return err
+	}
+
+	if filename, err = writeCertificate(c.Config.ConfigPath, cert); err != nil {
+		return err
+	}
+
+	fmt.Printf("Certificate configuration written to %s\n", filename)
+	return nil
+}
+
+// writeCertificate writes the certificate configuration to <path>/<name>.yaml, existing files are never overwritten
+func writeCertificate(path string, cert config.Certificate) (string, error) {
+	var (
+		err  error
+		data []byte
+		f    *os.File
+	)
+
+	if data, err = yaml.Marshal(cert); err != nil {
+		slog.Error("could not marshal certificate configuration", "certificate", cert.Name, "error", err)
+		return "", err
+	}
+
+	filename := filepath.Join(path, cert.Name+".yaml")
+	if err = os.MkdirAll(path, 0o755); err != nil {
+		slog.Error("could not create config path", "path", path, "error", err)
+		return "", err
+	}
+
+	if f, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644); err != nil {
+		slog.Error("could not create certificate configuration", "file", filename, "error", err)
+		return "", err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		slog.Error("could not write certificate configuration", "file", filename, "error", err)
+		return "", err
+	}
+	return filename, f.Close()
+}
+
+func (c ConfigureCertificate) askCertificate(p prompter) (config.Certificate, error) {
+	var (
+		err          error
+		output       config.Certificate
+		installation config.Installation
+	)
+
+	if output.Name, err = c.askName(p); err != nil {
+		return output, err
+	}
+
+	if output.Request.Content.CommonName, err = p.AskRequired("Common name", ""); err != nil {
+		return output, err
+	}
+	if output.Request.Content.SubjectAlternativeNames, err = p.AskList("Subject alternative names, separated by a comma"); err != nil {
+		return output, err
+	}
+
+	if output.Request.Challenge.Service, err = c.askService(p); err != nil {
+		return output, err
+	}
+
+	if output.Request.User, err = c.askUser(p); err != nil {
+		return output, err
+	}
+
+	if output.Request.Challenge, err = c.askChallenge(p, output.Request.Challenge); err != nil {
+		return output, err
+	}
+
+	fmt.Println("Request target, used to execute the challenge")
+	if output.Request.Target, err = c.askTarget(p, config.Target{}); err != nil {
+		return output, err
+	}
+
+	keyTypes := []string{config.ACME_KEY_TYPE_RSA2048, config.ACME_KEY_TYPE_RSA4096, config.ACME_KEY_TYPE_RSA8192, config.ACME_KEY_TYPE_EC256, config.ACME_KEY_TYPE_EC384}
+	var i int
+	if i, err = p.Choose("Key type", keyTypes, 1); err != nil {
+		return output, err
+	}
+	output.Request.KeyType = keyTypes[i]
+
+	for {
+		fmt.Println("Installation target")
+		if installation, err = c.askInstallation(p, output.Request.Target); err != nil {
+			return output, err
+		}
+		output.Installation = append(output.Installation, installation)
+
+		var more bool
+		if more, err = p.Confirm("Install the certificate on another target?", false); err != nil || !more {
+			return output, err
+		}
+	}
+}
+
+func (c ConfigureCertificate) askName(p prompter) (string, error) {
+	var (
+		err    error
+		output string
+		loader = controllers.NewLoader(c.Config.ConfigPath, c.Config.NameFromFilename)
+	)
+
+	for {
+		if output, err = p.AskRequired("Certificate name", ""); err != nil {
+			return "", err
+		}
+
+		// The loader fails when the name is not found, which is what we expect for a new certificate
+		if _, err = loader.Get(output); err == nil {
+			fmt.Printf("Certificate %s already exists in %s\n", output, c.Config.ConfigPath)
+			continue
+		}
+		if _, err = os.Stat(filepath.Join(c.Config.ConfigPath, output+".yaml")); err == nil {
+			fmt.Printf("File %s.yaml already exists in %s\n", output, c.Config.ConfigPath)
+			continue
+		}
+		return output, nil
+	}
+}
+
+func (c ConfigureCertificate) askService(p prompter) (string, error) {
+	var (
+		err error
+		i   int
+	)
+
+	services := []string{config.ACME_SERVICE_LETSENCRYPT_PRODUCTION, config.ACME_SERVICE_LETSENCRYPT_STAGING, ACME_SERVICE_CUSTOM}
+	if i, err = p.Choose("ACME service", services, 1); err != nil {
+		return "", err
+	}
+	if services[i] == ACME_SERVICE_CUSTOM {
+		return p.AskRequired("Directory URL of the ACME service", "")
+	}
+	return services[i], nil
+}
+
+func (c ConfigureCertificate) askUser(p prompter) (string, error) {
+	var (
+		err   error
+		i     int
+		users []string
+	)
+
+	for _, u := range c.Config.Users {
+		users = append(users, u.Name+" ("+u.Email+")")
+	}
+	if len(users) == 0 {
+		return "", fmt.Errorf("no users defined in the global configuration")
+	}
+
+	if i, err = p.Choose("User", users, 0); err != nil {
+		return "", err
+	}
+	return c.Config.Users[i].Name, nil
+}
+
+func (c ConfigureCertificate) askChallenge(p prompter, challenge config.Challenge) (config.Challenge, error) {
+	var (
+		err       error
+		i         int
+		providers []string
+	)
+
+	types := []string{config.ACME_CHALLENGE_TYPE_HTTP, config.ACME_CHALLENGE_TYPE_DNS}
+	if i, err = p.Choose("Challenge type", types, 0); err != nil {
+		return challenge, err
+	}
+	challenge.Type = types[i]
+
+	switch challenge.Type {
+	case config.ACME_CHALLENGE_TYPE_HTTP:
+		providers = []string{netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, webserver.ACME_CHALLENGE_PROVIDER_WEBSERVER}
+	case config.ACME_CHALLENGE_TYPE_DNS:
+		providers = []string{netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, ACME_CHALLENGE_PROVIDER_LEGO_DNS}
+	}
+
+	if i, err = p.Choose("Challenge provider", providers, 0); err != nil {
+		return challenge, err
+	}
+	challenge.Provider = providers[i]
+
+	if challenge.Provider != ACME_CHALLENGE_PROVIDER_LEGO_DNS {
+		return challenge, nil
+	}
+
+	if challenge.Provider, err = p.AskRequired("Name of the lego dns provider, see https://go-acme.github.io/lego/dns/", ""); err != nil {
+		return challenge, err
+	}
+
+	if len(c.Config.Parameters) == 0 {
+		return challenge, nil
+	}
+	parameters := []string{"<none>"}
+	for _, parameter := range c.Config.Parameters {
+		parameters = append(parameters, parameter.Name)
+	}
+	if i, err = p.Choose("Provider parameters", parameters, 0); err != nil {
+		return challenge, err
+	}
+	if i > 0 {
+		challenge.ProviderParameters = parameters[i]
+	}
+	return challenge, nil
+}
+
+// askTarget asks for an organization, environment and partition from the global configuration, defaultTarget is selected by default
+func (c ConfigureCertificate) askTarget(p prompter, defaultTarget config.Target) (config.Target, error) {
+	var (
+		err           error
+		i             int
+		organization  registry.Organization
+		organizations []string
+		environments  []string
+		partition     string
+	)
+
+	if len(c.Config.Organizations) == 0 {
+		return config.Target{}, fmt.Errorf("no organizations defined in the global configuration")
+	}
+
+	defaultIndex := 0
+	for k, o := range c.Config.Organizations {
+		organizations = append(organizations, o.Name)
+		if o.Name == defaultTarget.Organization {
+			defaultIndex = k
+		}
+	}
+	if i, err = p.Choose("Organization", organizations, defaultIndex); err != nil {
+		return config.Target{}, err
+	}
+	organization = c.Config.Organizations[i]
+
+	defaultIndex = 0
+	for k, e := range organization.Environments {
+		environments = append(environments, e.Name)
+		if e.Name == defaultTarget.Environment {
+			defaultIndex = k
+		}
+	}
+	if i, err = p.Choose("Environment", environments, defaultIndex); err != nil {
+		return config.Target{}, err
+	}
+
+	if partition, err = p.Ask("Admin partition (leave empty for the default partition)", defaultTarget.Partition); err != nil {
+		return config.Target{}, err
+	}
+
+	return config.Target{
+		Organization: organization.Name,
+		Environment:  organization.Environments[i].Name,
+		Partition:    partition,
+	}, nil
+}
+
+func (c ConfigureCertificate) askInstallation(p prompter, defaultTarget config.Target) (config.Installation, error) {
+	var (
+		err         error
+		output      config.Installation
+		client      *nitro.Client
+		vservers    []string
+		services    []string
+		selected    []int
+		sniEnabled  bool
+		environment registry.Environment
+	)
+
+	if output.Target, err = c.askTarget(p, defaultTarget); err != nil {
+		return output, err
+	}
+
+	if environment, err = c.getEnvironment(output.Target); err != nil {
+		return output, err
+	}
+
+	slog.Info("retrieving ssl endpoints", "organization", output.Target.Organization, "environment", output.Target.Environment)
+	if client, err = environment.GetPrimaryNitroClient(); err != nil {
+		slog.Error("could not connect to environment", "organization", output.Target.Organization, "environment", output.Target.Environment, "error", err)
+		return output, err
+	}
+	if err = netscaleradc.SwitchPartition(client, output.Target.Partition); err != nil {
+		slog.Error("could not switch partition", "organization", output.Target.Organization, "environment", output.Target.Environment, "partition", output.Target.Partition, "error", err)
+		return output, err
+	}
+
+	if vservers, err = c.getSslVirtualServers(client); err != nil {
+		return output, err
+	}
+	if services, err = c.getSslServices(client); err != nil {
+		return output, err
+	}
+
+	if selected, err = p.ChooseMultiple("SSL virtual servers", vservers); err != nil {
+		return output, err
+	}
+	for _, i := range selected {
+		if sniEnabled, err = p.Confirm("Enable SNI for "+vservers[i]+"?", true); err != nil {
+			return output, err
+		}
+		output.AddSslVirtualServer(vservers[i], sniEnabled)
+	}
+
+	if selected, err = p.ChooseMultiple("SSL services", services); err != nil {
+		return output, err
+	}
+	for _, i := range selected {
+		if sniEnabled, err = p.Confirm("Enable SNI for "+services[i]+"?", false); err != nil {
+			return output, err
+		}
+		output.AddSslService(services[i], sniEnabled)
+	}
+
+	if output.ReplaceDefaultCertificate, err = p.Confirm("Replace the default NetScaler ADC certificate (ns-server-certificate)?", false); err != nil {
+		return output, err
+	}
+	if output.ReplaceDefaultCertificate {
+		if output.SafeReplace, err = p.Confirm("Revert the default certificate if NITRO over HTTPS fails after replacing it?", true); err != nil {
+			return output, err
+		}
+	}
+	if output.SafeReplace {
+		if output.RevertOverHttp, err = p.Confirm("Revert the default certificate over plain HTTP, sending credentials unencrypted?", false); err != nil {
+			return output, err
+		}
+	}
+
+	if len(output.SslVirtualServers) == 0 && len(output.SslServices) == 0 && !output.ReplaceDefaultCertificate {
+		slog.Warn("certificate will be installed without any bindings", "organization", output.Target.Organization, "environment", output.Target.Environment)
+	}
+	return output, nil
+}
+
+func (c ConfigureCertificate) getEnvironment(target config.Target) (registry.Environment, error) {
+	for _, o := range c.Config.Organizations {
+		if o.Name != target.Organization {
+			continue
+		}
+		for _, e := range o.Environments {
+			if e.Name == target.Environment {
+				return e, nil
+			}
+		}
+	}
+	return registry.Environment{}, fmt.Errorf("could not find environment %s in organization %s", target.Environment, target.Organization)
+}
+
+func (c ConfigureCertificate) getSslVirtualServers(client *nitro.Client) ([]string, error) {
+	var (
+		err      error
+		output   []string
+		response *nitro.Response[nitroConfig.SslVserver]
+	)
+
+	request := &nitro.Request[nitroConfig.SslVserver]{
+		Attributes: []string{"vservername"},
+	}
+	if response, err = nitro.ExecuteNitroRequest[nitroConfig.SslVserver](client, request); err != nil {
+		slog.Error("could not retrieve ssl virtual servers", "error", err)
+		return nil, fmt.Errorf("could not retrieve ssl virtual servers with message %w", err)
+	}
+
+	for _, v := range response.Data {
+		output = append(output, v.VserverName)
+	}
+	return output, nil
+}
+
+func (c ConfigureCertificate) getSslServices(client *nitro.Client) ([]string, error) {
+	var (
+		err      error
+		output   []string
+		response *nitro.Response[nitroConfig.SslService]
+	)
+
+	request := &nitro.Request[nitroConfig.SslService]{
+		Attributes: []string{"servicename"},
+	}
+	if response, err = nitro.ExecuteNitroRequest[nitroConfig.SslService](client, request); err != nil {
+		slog.Error("could not retrieve ssl services", "error", err)
+		return nil, fmt.Errorf("could not retrieve ssl services with message %w", err)
+	}
+
+	for _, s := range response.Data {
+		output = append(output, s.ServiceName)
+	}
+	return output, nil
 }
@@ -17,14 +17,24 @@
 package command
 
 import (
-	"fmt"
+	"log/slog"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
 )
 
 type ConfigureCertificateExample struct {
+	Output string
 }
 
 func (c ConfigureCertificateExample) Execute() error {
-	var err error
-	fmt.Println("Configure certificate settings example")
-	return err
+	var (
+		err  error
+		data []byte
+	)
+
+	if data, err = config.GetCertificateExample(); err != nil {
+		slog.Error("could not generate certificate configuration example", "error", err)
+		return err
+	}
+	return writeExample(c.Output, data)
 }
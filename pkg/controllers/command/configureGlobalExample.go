@@ -17,14 +17,24 @@
 package command
 
 import (
-	"fmt"
+	"log/slog"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
 )
 
 type ConfigureGlobalExample struct {
+	Output string
 }
 
 func (c ConfigureGlobalExample) Execute() error {
-	var err error
-	fmt.Println("Configure global settings example")
-	return err
+	var (
+		err  error
+		data []byte
+	)
+
+	if data, err = config.GetApplicationExample(); err != nil {
+		slog.Error("could not generate global configuration example", "error", err)
+		return err
+	}
+	return writeExample(c.Output, data)
 }
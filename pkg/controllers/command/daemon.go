@@ -32,7 +32,7 @@ package command
 // 	}
 // 	slog.Info("Running daemon", "address", c.Config.Daemon.Address, "port", c.Config.Daemon.Port)
 //
-// 	// launcher, err = controllers.NewLauncher(c.Config.ConfigPath, c.Config.Organizations, c.Config.Users)
+// 	// launcher, err = controllers.NewLauncher(controllers.NewLoader(c.Config.ConfigPath, c.Config.NameFromFilename), c.Config.Organizations, c.Config.Users)
 // 	// return launcher.RequestAll()
 // 	return nil
 // }
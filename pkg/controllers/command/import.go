@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package command
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+// Import generates certificate configurations for the certificates on a target which are not managed by lens
+type Import struct {
+	Config    config.Application
+	Target    config.Target
+	User      string
+	Challenge config.Challenge
+	Output    string
+	Takeover  bool
+}
+
+func (c Import) Execute() error {
+	var (
+		err      error
+		launcher *controllers.Launcher
+		certs    []config.Certificate
+		skipped  []controllers.SkippedCertKey
+		imported int
+	)
+
+	launcher = controllers.NewLauncher(controllers.NewLoader(c.Config.ConfigPath, c.Config.NameFromFilename), c.Config.Organizations, c.Config.Users, c.Config.Parameters)
+	if certs, skipped, err = launcher.Import(c.Target); err != nil {
+		return err
+	}
+
+	output := c.Output
+	if output == "" {
+		output = c.Config.ConfigPath
+	}
+
+	for _, cert := range certs {
+		var filename string
+
+		cert.Request.User = c.User
+		cert.Request.Challenge = c.Challenge
+
+		// Imported certificates are named after their certkey, which lens keeps using when taking over the certkey
+		if c.Takeover {
+			for i := range cert.Installation {
+				cert.Installation[i].CertKeyName = cert.Name
+			}
+		}
+
+		if err = cert.Validate(); err != nil {
+			slog.Warn("skipping invalid certificate configuration", "certificate", cert.Name, "error", err)
+			skipped = append(skipped, controllers.SkippedCertKey{CertKey: cert.Name, Err: err})
+			continue
+		}
+
+		if c.Challenge.Type == config.ACME_CHALLENGE_TYPE_HTTP && c.hasWildcard(cert) {
+			slog.Warn("wildcard certificates require a dns-01 challenge, update the challenge before requesting the certificate", "certificate", cert.Name)
+		}
+
+		if filename, err = writeCertificate(output, cert); err != nil {
+			slog.Warn("skipping certificate configuration", "certificate", cert.Name, "error", err)
+			skipped = append(skipped, controllers.SkippedCertKey{CertKey: cert.Name, Err: err})
+			continue
+		}
+		slog.Info("certificate configuration written", "certificate", cert.Name, "file", filename)
+		imported++
+	}
+
+	slog.Info("import completed", "target", c.Target, "found", len(certs), "imported", imported, "skipped", len(skipped))
+
+	// Skipped certkeys are printed, so they do not go unnoticed when only warnings are logged
+	fmt.Printf("Imported %d certificate configurations to %s\n", imported, output)
+	for _, s := range skipped {
+		fmt.Printf("Skipped certkey %s: %s\n", s.CertKey, s.Err)
+	}
+	return nil
+}
+
+func (c Import) hasWildcard(cert config.Certificate) bool {
+	domains, _ := cert.Request.ListDomains()
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// prompter asks questions to the operator on the command line
+type prompter struct {
+	reader *bufio.Reader
+	writer io.Writer
+}
+
+func newPrompter(r io.Reader, w io.Writer) prompter {
+	return prompter{
+		reader: bufio.NewReader(r),
+		writer: w,
+	}
+}
+
+// Ask returns the answer to question, or defaultValue when the answer is empty
+func (p prompter) Ask(question string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		question = question + " [" + defaultValue + "]"
+	}
+	if _, err := fmt.Fprint(p.writer, question+": "); err != nil {
+		return "", err
+	}
+
+	answer, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", fmt.Errorf("could not read answer with message %w", err)
+	}
+
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue, nil
+	}
+	return answer, nil
+}
+
+// AskRequired asks question until a non-empty answer is given
+func (p prompter) AskRequired(question string, defaultValue string) (string, error) {
+	for {
+		answer, err := p.Ask(question, defaultValue)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		fmt.Fprintln(p.writer, "A value is required")
+	}
+}
+
+// AskList returns the comma separated values of the answer to question
+func (p prompter) AskList(question string) ([]string, error) {
+	var output []string
+
+	answer, err := p.Ask(question, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range strings.Split(answer, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			output = append(output, v)
+		}
+	}
+	return output, nil
+}
+
+// Confirm asks a yes/no question
+func (p prompter) Confirm(question string, defaultValue bool) (bool, error) {
+	d := "y/N"
+	if defaultValue {
+		d = "Y/n"
+	}
+
+	for {
+		answer, err := p.Ask(question+" ("+d+")", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultValue, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.writer, "Answer yes or no")
+	}
+}
+
+// Choose lists options and returns the index of the selected option, defaultIndex is used when the answer is empty
+func (p prompter) Choose(question string, options []string, defaultIndex int) (int, error) {
+	if len(options) == 0 {
+		return -1, fmt.Errorf("no options available for %s", question)
+	}
+
+	p.printOptions(question, options)
+	for {
+		answer, err := p.Ask("Select an option", strconv.Itoa(defaultIndex+1))
+		if err != nil {
+			return -1, err
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(options) {
+			return i - 1, nil
+		}
+		fmt.Fprintf(p.writer, "Select a number between 1 and %d\n", len(options))
+	}
+}
+
+// ChooseMultiple lists options and returns the indexes of the selected options, which may be none
+func (p prompter) ChooseMultiple(question string, options []string) ([]int, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	p.printOptions(question, options)
+	for {
+		var (
+			output  []int
+			invalid bool
+		)
+
+		answers, err := p.AskList("Select options, separated by a comma, or leave empty to skip")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, answer := range answers {
+			i, err := strconv.Atoi(answer)
+			if err != nil || i < 1 || i > len(options) {
+				invalid = true
+				break
+			}
+			output = append(output, i-1)
+		}
+
+		if !invalid {
+			return output, nil
+		}
+		fmt.Fprintf(p.writer, "Select numbers between 1 and %d\n", len(options))
+	}
+}
+
+func (p prompter) printOptions(question string, options []string) {
+	fmt.Fprintln(p.writer, question)
+	for i, option := range options {
+		fmt.Fprintf(p.writer, "  %d) %s\n", i+1, option)
+	}
+}
@@ -27,19 +27,20 @@ type Request struct {
 	Config     config.Application
 	Request    string
 	RequestAll bool
+	Force      bool
 }
 
 func (c Request) Execute() error {
 	var (
 		launcher *controllers.Launcher
 	)
-	launcher = controllers.NewLauncher(c.Config.ConfigPath, c.Config.Organizations, c.Config.Users, c.Config.Parameters)
+	launcher = controllers.NewLauncher(controllers.NewLoader(c.Config.ConfigPath, c.Config.NameFromFilename), c.Config.Organizations, c.Config.Users, c.Config.Parameters)
 
 	if c.Request != "" {
-		return launcher.Request(c.Request)
+		return launcher.Request(c.Request, c.Force)
 	}
 	if c.RequestAll {
-		return launcher.RequestAll()
+		return launcher.RequestAll(c.Force)
 
 	}
 	return fmt.Errorf("no valid execution target")
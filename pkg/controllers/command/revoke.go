@@ -0,0 +1,43 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/controllers"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+type Revoke struct {
+	Config config.Application
+	Name   string
+	Reason uint
+	Remove bool
+}
+
+func (c Revoke) Execute() error {
+	var (
+		launcher *controllers.Launcher
+	)
+	launcher = controllers.NewLauncher(controllers.NewLoader(c.Config.ConfigPath, c.Config.NameFromFilename), c.Config.Organizations, c.Config.Users, c.Config.Parameters)
+
+	if c.Name != "" {
+		return launcher.Revoke(c.Name, c.Reason, c.Remove)
+	}
+	return fmt.Errorf("no valid execution target")
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro"
+	nitroConfig "github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/config"
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/controllers"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+// installationEndpoint is an ssl vserver, ssl service, ssl service group or ssl profile defined in an installation
+type installationEndpoint struct {
+	Endpoint string
+	Name     string
+}
+
+// getInstallationEndpoints returns the ssl vservers, ssl services, ssl service groups and ssl profiles of the installation
+func (l Launcher) getInstallationEndpoints(i config.Installation) []installationEndpoint {
+	var output []installationEndpoint
+
+	for _, e := range i.SslVirtualServers {
+		output = append(output, installationEndpoint{Endpoint: BINDING_ENDPOINT_VSERVER, Name: e.Name})
+	}
+	for _, e := range i.SslServices {
+		output = append(output, installationEndpoint{Endpoint: BINDING_ENDPOINT_SERVICE, Name: e.Name})
+	}
+	for _, e := range i.SslServiceGroups {
+		output = append(output, installationEndpoint{Endpoint: BINDING_ENDPOINT_SERVICEGROUP, Name: e.Name})
+	}
+	for _, e := range i.SslProfiles {
+		output = append(output, installationEndpoint{Endpoint: BINDING_ENDPOINT_PROFILE, Name: e.Name})
+	}
+	return output
+}
+
+// endpointCertKeyBinding is a certkey binding as seen from the endpoint the certkey is bound to
+// Only the endpoint side of the binding reports whether the certkey is bound as SNI or CA certificate
+type endpointCertKeyBinding struct {
+	CertKeyName string
+	SniCert     bool
+	Ca          bool
+	OcspCheck   string
+	CrlCheck    string
+}
+
+// getEndpointCertKeyBindings returns the certkeys bound to the ssl vserver, ssl service, ssl service group or ssl profile
+func (l Launcher) getEndpointCertKeyBindings(c *nitro.Client, endpoint string, name string) ([]endpointCertKeyBinding, error) {
+	var (
+		err        error
+		output     []endpointCertKeyBinding
+		attributes = []string{"certkeyname", "snicert", "ca", "ocspcheck", "crlcheck"}
+	)
+
+	switch endpoint {
+	case BINDING_ENDPOINT_VSERVER:
+		var res *nitro.Response[nitroConfig.SslVserverSslCertKeyBinding]
+		if res, err = nitro.ExecuteNitroRequest[nitroConfig.SslVserverSslCertKeyBinding](c, &nitro.Request[nitroConfig.SslVserverSslCertKeyBinding]{ResourceName: name, Attributes: attributes}); err != nil {
+			return nil, err
+		}
+		for _, b := range res.Data {
+			output = append(output, endpointCertKeyBinding{CertKeyName: b.CertKeyName, SniCert: b.SniCert, Ca: b.Ca, OcspCheck: b.OcspCheck, CrlCheck: b.CrlCheck})
+		}
+	case BINDING_ENDPOINT_SERVICE:
+		var res *nitro.Response[nitroConfig.SslServiceSslCertKeyBinding]
+		if res, err = nitro.ExecuteNitroRequest[nitroConfig.SslServiceSslCertKeyBinding](c, &nitro.Request[nitroConfig.SslServiceSslCertKeyBinding]{ResourceName: name, Attributes: attributes}); err != nil {
+			return nil, err
+		}
+		for _, b := range res.Data {
+			output = append(output, endpointCertKeyBinding{CertKeyName: b.CertKeyName, SniCert: b.SniCert, Ca: b.Ca, OcspCheck: b.OcspCheck, CrlCheck: b.CrlCheck})
+		}
+	case BINDING_ENDPOINT_SERVICEGROUP:
+		var res *nitro.Response[nitroConfig.SslServiceGroupSslCertKeyBinding]
+		if res, err = nitro.ExecuteNitroRequest[nitroConfig.SslServiceGroupSslCertKeyBinding](c, &nitro.Request[nitroConfig.SslServiceGroupSslCertKeyBinding]{ResourceName: name, Attributes: attributes}); err != nil {
+			return nil, err
+		}
+		for _, b := range res.Data {
+			output = append(output, endpointCertKeyBinding{CertKeyName: b.CertKeyName, SniCert: b.SniCert, Ca: b.Ca, OcspCheck: b.OcspCheck, CrlCheck: b.CrlCheck})
+		}
+	case BINDING_ENDPOINT_PROFILE:
+		var res *nitro.Response[nitroConfig.SslProfileSslCertKeyBinding]
+		if res, err = nitro.ExecuteNitroRequest[nitroConfig.SslProfileSslCertKeyBinding](c, &nitro.Request[nitroConfig.SslProfileSslCertKeyBinding]{ResourceName: name, Attributes: attributes}); err != nil {
+			return nil, err
+		}
+		for _, b := range res.Data {
+			output = append(output, endpointCertKeyBinding{CertKeyName: b.CertKeyName, SniCert: b.SniCert, Ca: b.Ca, OcspCheck: b.OcspCheck, CrlCheck: b.CrlCheck})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported endpoint %s for certkey bindings", endpoint)
+	}
+	return output, nil
+}
+
+// bindServerCertificate binds the certkey as server certificate to the ssl vserver, ssl service or ssl service group
+// Without SNI, an endpoint accepts a single server certificate, so the server certificate bound without SNI is replaced,
+// e.g. the certkey in use before the certificate was imported into lens. If binding fails, the replaced certkey is bound again.
+func (l Launcher) bindServerCertificate(c *nitro.Client, endpoint string, name string, certKeyName string, sniEnabled bool) error {
+	var (
+		err      error
+		bindings []endpointCertKeyBinding
+		replaced []string
+	)
+
+	if sniEnabled {
+		return l.bindEndpoint(c, endpoint, name, certKeyName, true)
+	}
+
+	if bindings, err = l.getEndpointCertKeyBindings(c, endpoint, name); err != nil {
+		return fmt.Errorf("could not get certkey bindings of %s %s with message %w", endpoint, name, err)
+	}
+	for _, boundTo := range bindings {
+		if boundTo.Ca || boundTo.SniCert || boundTo.CertKeyName == certKeyName {
+			continue
+		}
+		slog.Info("replacing server certificate bound without sni", "endpoint", endpoint, "name", name, "certkey", boundTo.CertKeyName, "replacement", certKeyName)
+		if err = l.unbindEndpoint(c, endpoint, name, boundTo.CertKeyName); err != nil {
+			err = fmt.Errorf("could not unbind server certificate %s from %s %s with message %w", boundTo.CertKeyName, endpoint, name, err)
+			break
+		}
+		replaced = append(replaced, boundTo.CertKeyName)
+	}
+
+	if err == nil {
+		err = l.bindEndpoint(c, endpoint, name, certKeyName, false)
+	}
+	if err != nil {
+		for _, certKey := range replaced {
+			slog.Warn("restoring replaced server certificate", "endpoint", endpoint, "name", name, "certkey", certKey)
+			if restoreErr := l.bindEndpoint(c, endpoint, name, certKey, false); restoreErr != nil {
+				slog.Error("could not restore replaced server certificate", "endpoint", endpoint, "name", name, "certkey", certKey, "error", restoreErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (l Launcher) bindEndpoint(c *nitro.Client, endpoint string, name string, certKeyName string, sniEnabled bool) error {
+	var (
+		err        error
+		controller = controllers.NewSslCertKeyController(c)
+	)
+
+	switch endpoint {
+	case BINDING_ENDPOINT_VSERVER:
+		_, err = controller.BindSslVserver(name, certKeyName, sniEnabled)
+	case BINDING_ENDPOINT_SERVICE:
+		_, err = controller.BindSslService(name, certKeyName, sniEnabled)
+	case BINDING_ENDPOINT_SERVICEGROUP:
+		_, err = controller.BindSslServiceGroup(name, certKeyName, sniEnabled)
+	case BINDING_ENDPOINT_PROFILE:
+		_, err = controller.BindSslProfile(name, certKeyName, sniEnabled)
+	default:
+		err = fmt.Errorf("unsupported endpoint %s for certkey bindings", endpoint)
+	}
+	return err
+}
+
+func (l Launcher) unbindEndpoint(c *nitro.Client, endpoint string, name string, certKeyName string) error {
+	var (
+		err        error
+		controller = controllers.NewSslCertKeyController(c)
+	)
+
+	switch endpoint {
+	case BINDING_ENDPOINT_VSERVER:
+		_, err = controller.UnbindSslVserver(name, certKeyName)
+	case BINDING_ENDPOINT_SERVICE:
+		_, err = controller.UnbindSslService(name, certKeyName)
+	case BINDING_ENDPOINT_SERVICEGROUP:
+		_, err = controller.UnbindSslServiceGroup(name, certKeyName)
+	case BINDING_ENDPOINT_PROFILE:
+		_, err = controller.UnbindSslProfile(name, certKeyName)
+	default:
+		err = fmt.Errorf("unsupported endpoint %s for certkey bindings", endpoint)
+	}
+	return err
+}
+
+// bindCaCertificateToEndpoint binds the CA certkey to the ssl vserver, ssl service, ssl service group or ssl profile
+// When the CA certkey is already bound with other OCSP or CRL checks, it is rebound with the requested checks.
+// If rebinding fails, the CA certkey is bound again with the previous checks.
+func (l Launcher) bindCaCertificateToEndpoint(c *nitro.Client, endpoint string, name string, caCertKeyName string, ocspCheck string, crlCheck string) error {
+	var (
+		err      error
+		bindings []endpointCertKeyBinding
+		previous *endpointCertKeyBinding
+	)
+
+	if bindings, err = l.getEndpointCertKeyBindings(c, endpoint, name); err != nil {
+		return fmt.Errorf("could not get certkey bindings of %s %s with message %w", endpoint, name, err)
+	}
+	for _, boundTo := range bindings {
+		if !boundTo.Ca || boundTo.CertKeyName != caCertKeyName {
+			continue
+		}
+		if boundTo.OcspCheck == ocspCheck && boundTo.CrlCheck == crlCheck {
+			slog.Debug("ca certificate already bound", "endpoint", endpoint, "name", name, "certkey", caCertKeyName)
+			return nil
+		}
+		previous = &boundTo
+		break
+	}
+
+	if previous != nil {
+		slog.Info("rebinding ca certificate with updated checks", "endpoint", endpoint, "name", name, "certkey", caCertKeyName, "ocspCheck", ocspCheck, "crlCheck", crlCheck, "previousOcspCheck", previous.OcspCheck, "previousCrlCheck", previous.CrlCheck)
+		if err = l.unbindCaEndpoint(c, endpoint, name, caCertKeyName); err != nil {
+			return fmt.Errorf("could not unbind ca certificate %s from %s %s with message %w", caCertKeyName, endpoint, name, err)
+		}
+	}
+
+	slog.Debug("bind ca certificate", "endpoint", endpoint, "name", name, "certkey", caCertKeyName)
+	if err = l.bindCaEndpoint(c, endpoint, name, caCertKeyName, ocspCheck, crlCheck); err != nil {
+		if previous != nil {
+			slog.Warn("restoring ca certificate binding", "endpoint", endpoint, "name", name, "certkey", caCertKeyName)
+			if restoreErr := l.bindCaEndpoint(c, endpoint, name, caCertKeyName, previous.OcspCheck, previous.CrlCheck); restoreErr != nil {
+				slog.Error("could not restore ca certificate binding", "endpoint", endpoint, "name", name, "certkey", caCertKeyName, "error", restoreErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (l Launcher) bindCaEndpoint(c *nitro.Client, endpoint string, name string, caCertKeyName string, ocspCheck string, crlCheck string) error {
+	var (
+		err        error
+		controller = controllers.NewSslCertKeyController(c)
+	)
+
+	switch endpoint {
+	case BINDING_ENDPOINT_VSERVER:
+		_, err = controller.BindSslVserverCaCertificate(name, caCertKeyName, ocspCheck, crlCheck)
+	case BINDING_ENDPOINT_SERVICE:
+		_, err = controller.BindSslServiceCaCertificate(name, caCertKeyName, ocspCheck, crlCheck)
+	case BINDING_ENDPOINT_SERVICEGROUP:
+		_, err = controller.BindSslServiceGroupCaCertificate(name, caCertKeyName, ocspCheck, crlCheck)
+	case BINDING_ENDPOINT_PROFILE:
+		_, err = controller.BindSslProfileCaCertificate(name, caCertKeyName, ocspCheck, crlCheck)
+	default:
+		err = fmt.Errorf("unsupported endpoint %s for ca certificate bindings", endpoint)
+	}
+	return err
+}
+
+func (l Launcher) unbindCaEndpoint(c *nitro.Client, endpoint string, name string, caCertKeyName string) error {
+	var (
+		err        error
+		controller = controllers.NewSslCertKeyController(c)
+	)
+
+	switch endpoint {
+	case BINDING_ENDPOINT_VSERVER:
+		_, err = controller.UnbindSslVserverCaCertificate(name, caCertKeyName)
+	case BINDING_ENDPOINT_SERVICE:
+		_, err = controller.UnbindSslServiceCaCertificate(name, caCertKeyName)
+	case BINDING_ENDPOINT_SERVICEGROUP:
+		_, err = controller.UnbindSslServiceGroupCaCertificate(name, caCertKeyName)
+	case BINDING_ENDPOINT_PROFILE:
+		_, err = controller.UnbindSslProfileCaCertificate(name, caCertKeyName)
+	default:
+		err = fmt.Errorf("unsupported endpoint %s for ca certificate bindings", endpoint)
+	}
+	return err
+}
@@ -0,0 +1,287 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro"
+	nitroConfig "github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/config"
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/controllers"
+	"github.com/go-acme/lego/v4/certcrypto"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+// SkippedCertKey is a certkey on the target which could not be imported
+type SkippedCertKey struct {
+	CertKey string
+	Err     error
+}
+
+// Import discovers the certkeys on the target which are not managed by lens yet
+// Each certkey with a private key is returned as a certificate configuration with its domains, key type and bindings,
+// the request settings are left empty and must be completed by the caller. Certkeys which cannot be imported are returned as skipped.
+func (l Launcher) Import(t config.Target) ([]config.Certificate, []SkippedCertKey, error) {
+	var (
+		err     error
+		client  *nitro.Client
+		res     *nitro.Response[nitroConfig.SslCertKey]
+		managed map[string]string
+		output  []config.Certificate
+		skipped []SkippedCertKey
+	)
+	slog.Info("discovering certificates", "target", t)
+
+	if client, err = l.getNitroClient(t); err != nil {
+		return nil, nil, err
+	}
+
+	if managed, err = l.getManagedCertKeyNames(t); err != nil {
+		slog.Warn("could not load certificate configurations, only skipping certkeys with the LENS_ prefix", "target", t, "error", err)
+	}
+
+	nitroRequest := &nitro.Request[nitroConfig.SslCertKey]{
+		Attributes: []string{"certkey", "cert", "key"},
+	}
+	if res, err = nitro.ExecuteNitroRequest[nitroConfig.SslCertKey](client, nitroRequest); err != nil {
+		slog.Error("could not list certificates", "target", t, "error", err)
+		return nil, nil, fmt.Errorf("could not list certificates in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+
+	for _, certKey := range res.Data {
+		var cert config.Certificate
+
+		switch {
+		case strings.HasPrefix(certKey.CertKey, "LENS_"):
+			slog.Debug("skipping certificate managed by lens", "target", t, "certkey", certKey.CertKey)
+			continue
+		case managed[certKey.CertKey] != "":
+			slog.Debug("skipping certificate managed by lens", "target", t, "certkey", certKey.CertKey, "certificate", managed[certKey.CertKey])
+			continue
+		case certKey.Key == "":
+			// Certificates without a private key are CA certificates, which are installed with the chain of the imported certificate
+			slog.Debug("skipping certificate without private key", "target", t, "certkey", certKey.CertKey)
+			continue
+		}
+
+		if cert, err = l.importCertKey(client, t, certKey); err != nil {
+			slog.Warn("could not import certificate", "target", t, "certkey", certKey.CertKey, "error", err)
+			skipped = append(skipped, SkippedCertKey{CertKey: certKey.CertKey, Err: err})
+			continue
+		}
+		output = append(output, cert)
+	}
+
+	slog.Info("discovering certificates completed", "target", t, "count", len(output), "skipped", len(skipped))
+	return output, skipped, nil
+}
+
+// getManagedCertKeyNames returns the certkey names of the loaded certificate configurations installed on the target,
+// including custom certkey names, mapped to the name of the certificate configuration
+func (l Launcher) getManagedCertKeyNames(t config.Target) (map[string]string, error) {
+	var (
+		err    error
+		certs  map[string]config.Certificate
+		output = make(map[string]string)
+	)
+
+	if certs, err = l.loader.GetAll(); err != nil {
+		return output, err
+	}
+
+	for _, cert := range certs {
+		var variants []config.Certificate
+		if variants, err = cert.SplitByKeyType(); err != nil {
+			return output, err
+		}
+		for _, variant := range variants {
+			for _, i := range variant.Installation {
+				if i.Target == t {
+					output[l.getSslCertKeyName(i, variant.Name)] = variant.Name
+				}
+			}
+		}
+	}
+	return output, nil
+}
+
+func (l Launcher) importCertKey(c *nitro.Client, t config.Target, certKey nitroConfig.SslCertKey) (config.Certificate, error) {
+	var (
+		err     error
+		content []byte
+		leaf    *x509.Certificate
+		keyType string
+		output  config.Certificate
+	)
+	slog.Debug("importing certificate", "target", t, "certkey", certKey.CertKey)
+
+	if content, err = l.downloadFile(c, t, certKey.Cert); err != nil {
+		return output, err
+	}
+
+	// Certificates on NetScaler ADC are stored in PEM or DER format
+	if leaf, err = certcrypto.ParsePEMCertificate(content); err != nil {
+		if leaf, err = x509.ParseCertificate(content); err != nil {
+			return output, fmt.Errorf("could not parse certificate %s with message %w", certKey.Cert, err)
+		}
+	}
+
+	if keyType, err = l.getImportKeyType(leaf); err != nil {
+		return output, err
+	}
+
+	output = config.Certificate{
+		Name: certKey.CertKey,
+		Request: config.Request{
+			Target:  t,
+			KeyType: keyType,
+			Content: config.Content{
+				CommonName: leaf.Subject.CommonName,
+			},
+		},
+	}
+
+	for _, domain := range leaf.DNSNames {
+		if domain != leaf.Subject.CommonName {
+			output.Request.Content.SubjectAlternativeNames = append(output.Request.Content.SubjectAlternativeNames, domain)
+		}
+	}
+	if output.Request.Content.CommonName == "" && len(output.Request.Content.SubjectAlternativeNames) > 0 {
+		output.Request.Content.CommonName = output.Request.Content.SubjectAlternativeNames[0]
+		output.Request.Content.SubjectAlternativeNames = output.Request.Content.SubjectAlternativeNames[1:]
+	}
+
+	installation := config.Installation{
+		Target: t,
+	}
+	if err = l.importBindings(c, t, certKey.CertKey, &installation); err != nil {
+		return output, err
+	}
+	output.Installation = []config.Installation{installation}
+	return output, nil
+}
+
+// importBindings adds the bindings of the certkey to the installation
+// SNI and CA certificates are only available on the endpoint side of the binding, so they are read from every endpoint the certkey
+// is bound to. As lens binds CA certificates to all endpoints of the installation, the CA certificates bound to any of these endpoints
+// are added to the installation.
+func (l Launcher) importBindings(c *nitro.Client, t config.Target, name string, i *config.Installation) error {
+	var (
+		err             error
+		sniEnabled      bool
+		vserverBindings *nitro.Response[nitroConfig.SslCertKeySslVserverBinding]
+		serviceBindings *nitro.Response[nitroConfig.SslCertKeyServiceBinding]
+		groupBindings   *nitro.Response[nitroConfig.SslCertKeyServiceGroupBinding]
+		profileBindings *nitro.Response[nitroConfig.SslCertKeySslProfileBinding]
+		controller      = controllers.NewSslCertKeyController(c)
+	)
+
+	if vserverBindings, err = controller.GetSslVserverBinding(name, nil); err != nil {
+		return fmt.Errorf("could not get vserver bindings for certificate %s in organization %s environment %s with message %w", name, t.Organization, t.Environment, err)
+	}
+	for _, binding := range vserverBindings.Data {
+		if sniEnabled, err = l.importEndpointBindings(c, t, BINDING_ENDPOINT_VSERVER, binding.ServerName, name, i); err != nil {
+			return err
+		}
+		i.AddSslVirtualServer(binding.ServerName, sniEnabled)
+	}
+
+	if serviceBindings, err = controller.GetServiceBinding(name, nil); err != nil {
+		return fmt.Errorf("could not get service bindings for certificate %s in organization %s environment %s with message %w", name, t.Organization, t.Environment, err)
+	}
+	for _, binding := range serviceBindings.Data {
+		if sniEnabled, err = l.importEndpointBindings(c, t, BINDING_ENDPOINT_SERVICE, binding.ServiceName, name, i); err != nil {
+			return err
+		}
+		i.AddSslService(binding.ServiceName, sniEnabled)
+	}
+
+	if groupBindings, err = controller.GetServiceGroupBinding(name, nil); err != nil {
+		return fmt.Errorf("could not get service group bindings for certificate %s in organization %s environment %s with message %w", name, t.Organization, t.Environment, err)
+	}
+	for _, binding := range groupBindings.Data {
+		if sniEnabled, err = l.importEndpointBindings(c, t, BINDING_ENDPOINT_SERVICEGROUP, binding.ServiceGroupName, name, i); err != nil {
+			return err
+		}
+		i.AddSslServiceGroup(binding.ServiceGroupName, sniEnabled)
+	}
+
+	if profileBindings, err = controller.GetSslProfileBinding(name, nil); err != nil {
+		return fmt.Errorf("could not get ssl profile bindings for certificate %s in organization %s environment %s with message %w", name, t.Organization, t.Environment, err)
+	}
+	for _, binding := range profileBindings.Data {
+		if sniEnabled, err = l.importEndpointBindings(c, t, BINDING_ENDPOINT_PROFILE, binding.SslProfileName, name, i); err != nil {
+			return err
+		}
+		i.AddSslProfile(binding.SslProfileName, sniEnabled)
+	}
+	return nil
+}
+
+// importEndpointBindings adds the CA certificates bound to the endpoint to the installation
+// and returns whether the certkey is bound to the endpoint as SNI certificate
+func (l Launcher) importEndpointBindings(c *nitro.Client, t config.Target, endpoint string, endpointName string, certKeyName string, i *config.Installation) (bool, error) {
+	var (
+		err        error
+		bindings   []endpointCertKeyBinding
+		sniEnabled bool
+	)
+
+	if bindings, err = l.getEndpointCertKeyBindings(c, endpoint, endpointName); err != nil {
+		return false, fmt.Errorf("could not get certificate bindings for %s %s in organization %s environment %s with message %w", endpoint, endpointName, t.Organization, t.Environment, err)
+	}
+	for _, binding := range bindings {
+		switch {
+		case binding.CertKeyName == certKeyName:
+			sniEnabled = binding.SniCert
+		case binding.Ca:
+			slog.Debug("importing ca certificate binding", "target", t, "certkey", certKeyName, "endpoint", endpoint, "name", endpointName, "ca", binding.CertKeyName)
+			i.AddCaCertificate(binding.CertKeyName, binding.OcspCheck, binding.CrlCheck)
+		}
+	}
+	return sniEnabled, nil
+}
+
+// getImportKeyType returns the key type of the certificate, using the nearest larger RSA key size lens supports
+func (l Launcher) getImportKeyType(cert *x509.Certificate) (string, error) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		switch bits := key.N.BitLen(); {
+		case bits <= 2048:
+			return config.ACME_KEY_TYPE_RSA2048, nil
+		case bits <= 4096:
+			return config.ACME_KEY_TYPE_RSA4096, nil
+		default:
+			return config.ACME_KEY_TYPE_RSA8192, nil
+		}
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return config.ACME_KEY_TYPE_EC256, nil
+		case elliptic.P384():
+			return config.ACME_KEY_TYPE_EC384, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported key type %s for certificate %s", cert.PublicKeyAlgorithm, cert.Subject.CommonName)
+}
@@ -17,11 +17,18 @@
 package controllers
 
 import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"log/slog"
+	"path"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,6 +36,7 @@ import (
 	nitroConfig "github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/config"
 	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/controllers"
 	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"github.com/go-acme/lego/v4/acme/api"
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/go-acme/lego/v4/challenge"
@@ -36,12 +44,14 @@ import (
 	"github.com/go-acme/lego/v4/lego"
 	"github.com/go-acme/lego/v4/registration"
 
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/netscaleradc"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/models"
 	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
 )
 
 const (
-	LENS_CERTIFICATE_PATH = "/nsconfig/ssl/LENS/"
+	LENS_CERTIFICATE_PATH     = "/nsconfig/ssl/LENS/"
+	LENS_DEFAULT_CERTKEY_NAME = "ns-server-certificate"
 )
 
 type Launcher struct {
@@ -59,9 +69,9 @@ type Launcher struct {
 	accounts             map[models.UserServiceLink]*models.Account
 }
 
-func NewLauncher(path string, organizations []registry.Organization, users []config.User, params []config.ProviderParameters) *Launcher {
+func NewLauncher(loader Loader, organizations []registry.Organization, users []config.User, params []config.ProviderParameters) *Launcher {
 	return &Launcher{
-		loader:               NewLoader(path),
+		loader:               loader,
 		organizations:        organizations,
 		users:                users,
 		providerParams:       params,
@@ -76,7 +86,7 @@ func NewLauncher(path string, organizations []registry.Organization, users []con
 	}
 }
 
-func (l Launcher) Request(name string) error {
+func (l Launcher) Request(name string, force bool) error {
 	var (
 		err   error
 		certs map[string]config.Certificate
@@ -86,10 +96,10 @@ func (l Launcher) Request(name string) error {
 		return err
 	}
 
-	return l.processCertificates(certs)
+	return l.processCertificates(certs, force)
 }
 
-func (l Launcher) RequestAll() error {
+func (l Launcher) RequestAll(force bool) error {
 	var (
 		err   error
 		certs map[string]config.Certificate
@@ -99,19 +109,67 @@ func (l Launcher) RequestAll() error {
 		return err
 	}
 
-	return l.processCertificates(certs)
+	return l.processCertificates(certs, force)
 }
 
-func (l Launcher) processCertificates(certs map[string]config.Certificate) error {
+func (l Launcher) Revoke(name string, reason uint, remove bool) error {
 	var (
+		err   error
+		certs map[string]config.Certificate
+	)
+	certs, err = l.loader.Get(name)
+	if err != nil {
+		return err
+	}
+
+	// Certificates with multiple key types are revoked for every key type
+	certs, err = l.splitByKeyType(certs)
+	if err != nil {
+		return err
+	}
+
+	// Every variant is revoked, even if revoking another variant failed
+	var errs []error
+	for _, c := range certs {
+		if err = l.revokeCertificate(c, reason); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !remove {
+			continue
+		}
+		for _, i := range c.Installation {
+			if err = l.removeCertificate(i, c.Name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err = errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.Info("finished revoking certificates")
+	return nil
+}
+
+func (l Launcher) processCertificates(certs map[string]config.Certificate, force bool) error {
+	var (
+		err           error
 		providers     = make(map[string]int)
 		installations = make(map[config.Target]int)
 
 		wgProvider     sync.WaitGroup
 		wgInstallation sync.WaitGroup
 		wgError        sync.WaitGroup
+		errs           []error
 	)
 
+	// Certificates with multiple key types are requested and installed as a separate certificate per key type
+	certs, err = l.splitByKeyType(certs)
+	if err != nil {
+		return err
+	}
+
 	for _, c := range certs {
 		if _, foundProvider := providers[c.Request.Challenge.Provider]; foundProvider {
 			slog.Debug("found provider", "certificate", c.Name, "provider", c.Request.Challenge.Provider)
@@ -137,7 +195,7 @@ func (l Launcher) processCertificates(certs map[string]config.Certificate) error
 		l.channelMapMutex.Lock()
 		l.providerChannels[k] = make(chan config.Certificate, v)
 		wgProvider.Add(1)
-		go l.certificateProviderProcessor(k, l.providerChannels[k], &wgProvider)
+		go l.certificateProviderProcessor(k, l.providerChannels[k], force, &wgProvider)
 		l.channelMapMutex.Unlock()
 	}
 
@@ -151,7 +209,7 @@ func (l Launcher) processCertificates(certs map[string]config.Certificate) error
 	}
 
 	wgError.Add(1)
-	go l.errorProcessor(&wgError)
+	go l.errorProcessor(&errs, &wgError)
 
 	// Push certificates to their respective provider channel
 	for _, c := range certs {
@@ -183,13 +241,39 @@ func (l Launcher) processCertificates(certs map[string]config.Certificate) error
 	// Error channel can be closed as soon as all installation processors have finished
 	close(l.errorChannel)
 	wgError.Wait()
-	// TODO ADD CHECK FOR ERRORS which occurred in errorProcessor
 
+	if err = errors.Join(errs...); err != nil {
+		slog.Error("finished processing certificates with errors", "count", len(errs))
+		return err
+	}
 	slog.Info("finished processing certificates")
 	return nil
 }
 
-func (l Launcher) certificateProviderProcessor(p string, ch <-chan config.Certificate, wg *sync.WaitGroup) {
+func (l Launcher) splitByKeyType(certs map[string]config.Certificate) (map[string]config.Certificate, error) {
+	var (
+		err      error
+		variants []config.Certificate
+		output   = make(map[string]config.Certificate, len(certs))
+	)
+
+	for _, c := range certs {
+		variants, err = c.SplitByKeyType()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range variants {
+			if _, found := output[v.Name]; found {
+				return nil, fmt.Errorf("duplicate certificate name %s after splitting certificate %s by key type", v.Name, c.Name)
+			}
+			slog.Debug("adding certificate", "certificate", v.Name, "keyType", v.Request.KeyType)
+			output[v.Name] = v
+		}
+	}
+	return output, nil
+}
+
+func (l Launcher) certificateProviderProcessor(p string, ch <-chan config.Certificate, force bool, wg *sync.WaitGroup) {
 	var (
 		err error
 	)
@@ -199,11 +283,15 @@ func (l Launcher) certificateProviderProcessor(p string, ch <-chan config.Certif
 	slog.Debug("launching provider processor", "provider", p)
 	for r := range ch {
 		slog.Debug("provider sequence started for certificate", "provider", p, "certificate", r.Name)
-		r.Resource, err = l.executeAcmeRequest(r)
+		r.Resource, err = l.executeAcmeRequest(r, force)
 		if err != nil {
 			l.errorChannel <- fmt.Errorf("error occurred while processing request for certificate %s using provider %s with message: %w", r.Name, p, err)
 			continue
 		}
+		if r.Resource == nil {
+			slog.Info("certificate renewal is not due, skipping installation", "provider", p, "certificate", r.Name)
+			continue
+		}
 		for _, i := range r.Installation {
 			slog.Debug("send certificate to installation processor", "provider", p, "certificate", r.Name, "target", i.Target)
 			l.channelMapMutex.Lock()
@@ -231,7 +319,7 @@ func (l Launcher) certificateInstallationProcessor(t config.Target, ch <-chan co
 		}
 		for _, i := range r.Installation {
 			if i.Target == t {
-				err = l.updateEnvironment(i, r.Name, r.Resource)
+				err = l.updateEnvironment(i, r)
 				if err != nil {
 					l.errorChannel <- fmt.Errorf("error occurred while processing request for certificate %s using target %s with message: %w", r.Name, t, err)
 					continue
@@ -242,11 +330,13 @@ func (l Launcher) certificateInstallationProcessor(t config.Target, ch <-chan co
 	slog.Debug("terminating installation processor", "target", t)
 }
 
-func (l Launcher) errorProcessor(wg *sync.WaitGroup) {
+// errorProcessor logs and collects the errors of the provider and installation processors until the error channel is closed
+func (l Launcher) errorProcessor(errs *[]error, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for err := range l.errorChannel {
 		slog.Error(err.Error())
+		*errs = append(*errs, err)
 	}
 }
 
@@ -257,21 +347,24 @@ func (l Launcher) getAccount(username string, url string) (*models.Account, erro
 		account *models.Account
 	)
 	l.userMutex.Lock()
+	defer l.userMutex.Unlock()
+
 	usl := models.UserServiceLink{
 		Username: username,
 		Url:      url,
 	}
 
 	if _, exists := l.accounts[usl]; !exists {
-		user, err = l.getUser(username)
+		if user, err = l.getUser(username); err != nil {
+			return nil, err
+		}
 		slog.Debug("creating user account", "username", username, "service", url)
 		account, err = models.NewAccount(user.Email, user.ExternalAccountBinding)
 		if err != nil {
-			return nil, fmt.Errorf("could not create user for %s on service %s", username, url)
+			return nil, fmt.Errorf("could not create user for %s on service %s with message %w", username, url, err)
 		}
 		l.accounts[usl] = account
 	}
-	l.userMutex.Unlock()
 	return l.accounts[usl], nil
 }
 
@@ -293,6 +386,11 @@ func (l Launcher) getLegoClient(username string, url string, keyType certcrypto.
 
 	l.registrationMutex.Lock()
 	slog.Debug("locking for acme user account validation", "user", username, "service", url)
+	defer func() {
+		l.registrationMutex.Unlock()
+		slog.Debug("unlocking for acme user account validation", "user", username, "service", url)
+	}()
+
 	account, err = l.getAccount(username, url)
 	if err != nil {
 		slog.Debug("could not find user", "username", username, "service", url)
@@ -335,16 +433,15 @@ func (l Launcher) getLegoClient(username string, url string, keyType certcrypto.
 		}
 		account.Registration = reg
 	}
-	l.registrationMutex.Unlock()
-	slog.Debug("unlocking for acme user account validation", "user", username, "service", url)
-
 	return client, nil
 }
 
-func (l Launcher) executeAcmeRequest(cert config.Certificate) (*certificate.Resource, error) {
+func (l Launcher) executeAcmeRequest(cert config.Certificate, force bool) (*certificate.Resource, error) {
 	var (
 		err     error
 		client  *lego.Client
+		leaf    *x509.Certificate
+		issuer  *x509.Certificate
 		domains []string
 	)
 	slog.Info("execute acme request for certificate", "certificate", cert.Name)
@@ -354,6 +451,16 @@ func (l Launcher) executeAcmeRequest(cert config.Certificate) (*certificate.Reso
 		return nil, err
 	}
 
+	// Renewal information is only available when the certificate and its issuer are installed
+	leaf, issuer, err = l.getInstalledCertificateChain(cert)
+	if err != nil {
+		slog.Warn("could not retrieve installed certificate, renewal information is not available", "certificate", cert.Name, "error", err)
+	}
+
+	if leaf != nil && issuer != nil && !force && !l.isRenewalDue(client, cert.Name, leaf, issuer) {
+		return nil, nil
+	}
+
 	var environment registry.Environment
 	environment, err = l.getEnvironment(cert.Request.Target)
 	if err != nil {
@@ -406,165 +513,373 @@ func (l Launcher) executeAcmeRequest(cert config.Certificate) (*certificate.Reso
 		return nil, err
 	}
 
-	// Get domains for ACME request
-	if domains, err = cert.Request.GetDomains(); err != nil {
-		slog.Debug("invalid domain in request", "certificate", cert.Name, "error", err)
-		return nil, fmt.Errorf("invalid domain in request for certificate %s with message: %w", cert.Name, err)
+	// The domains are resolved once for the order, so validation does not repeat the DNS lookups of the request
+	var certificates *certificate.Resource
+	if cert.Request.CsrFile != "" {
+		certificates, err = l.obtainForCsr(client, cert)
+	} else {
+		if domains, err = cert.Request.GetDomains(); err != nil {
+			slog.Debug("invalid domain in request", "certificate", cert.Name, "error", err)
+			return nil, fmt.Errorf("invalid domain in request for certificate %s with message: %w", cert.Name, err)
+		}
+		certificates, err = l.obtain(client, cert, domains)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The certificate is not sent to any installation target when validation fails
+	if err = l.validateCertificate(cert, domains, certificates); err != nil {
+		return nil, err
+	}
+
+	if cert.Request.PreferredChain != "" {
+		var chain []*x509.Certificate
+		chain, err = certcrypto.ParsePEMBundle(certificates.IssuerCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse issuer certificate chain for certificate %s with message %w", cert.Name, err)
+		}
+		// lego falls back to the default chain when no alternate chain matches, which is still a valid chain for the certificate
+		// A warning is logged instead of failing the request, so certificates keep being renewed when an ACME service retires a chain
+		if !l.hasPreferredChain(chain, cert.Request.PreferredChain) {
+			slog.Warn("preferred chain not offered by acme service, using default chain", "certificate", cert.Name, "preferredChain", cert.Request.PreferredChain, "chain", chain[len(chain)-1].Issuer.CommonName)
+		} else {
+			slog.Debug("using preferred chain", "certificate", cert.Name, "preferredChain", cert.Request.PreferredChain)
+		}
+	}
+
+	return certificates, nil
+}
+
+// hasPreferredChain checks the issuer common name of every certificate in the chain, the same way lego matches the preferred chain
+func (l Launcher) hasPreferredChain(chain []*x509.Certificate, preferredChain string) bool {
+	for _, c := range chain {
+		if c.Issuer.CommonName == preferredChain {
+			return true
+		}
+	}
+	return false
+}
+
+// isRenewalDue asks the ACME service for the suggested renewal window of the installed certificate using ACME Renewal Information (ARI)
+// If the ACME service does not support ARI, the certificate is always renewed
+// A failing ARI lookup never blocks renewal, the certificate is renewed once two thirds of its lifetime has passed
+func (l Launcher) isRenewalDue(client *lego.Client, name string, leaf *x509.Certificate, issuer *x509.Certificate) bool {
+	var (
+		err     error
+		info    *certificate.RenewalInfoResponse
+		renewAt *time.Time
+	)
+
+	info, err = client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{
+		Cert:     leaf,
+		Issuer:   issuer,
+		HashName: crypto.SHA256.String(),
+	})
+	if err != nil {
+		if errors.Is(err, api.ErrNoARI) {
+			slog.Debug("acme service does not support renewal information", "certificate", name)
+			return true
+		}
+		slog.Warn("could not get renewal information, falling back to certificate lifetime", "certificate", name, "error", err)
+		return l.isLifetimeRenewalDue(name, leaf, time.Now())
+	}
+	slog.Debug("renewal information", "certificate", name, "start", info.SuggestedWindow.Start, "end", info.SuggestedWindow.End, "explanation", info.ExplanationURL)
+
+	renewAt = info.ShouldRenewAt(time.Now(), 0)
+	if renewAt == nil {
+		slog.Info("certificate renewal is not due", "certificate", name, "start", info.SuggestedWindow.Start, "end", info.SuggestedWindow.End)
+		return false
+	}
+	slog.Info("certificate renewal is due", "certificate", name, "start", info.SuggestedWindow.Start, "end", info.SuggestedWindow.End)
+	return true
+}
+
+// isLifetimeRenewalDue reports whether less than one third of the lifetime of the certificate remains at the given time
+func (l Launcher) isLifetimeRenewalDue(name string, leaf *x509.Certificate, now time.Time) bool {
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	renewAt := leaf.NotAfter.Add(-lifetime / 3)
+	if now.Before(renewAt) {
+		slog.Info("certificate renewal is not due", "certificate", name, "renewAt", renewAt)
+		return false
+	}
+	slog.Info("certificate renewal is due", "certificate", name, "renewAt", renewAt)
+	return true
+}
+
+func (l Launcher) obtain(client *lego.Client, cert config.Certificate, domains []string) (*certificate.Resource, error) {
+	var (
+		err          error
+		privateKey   crypto.PrivateKey
+		certificates *certificate.Resource
+	)
+
+	if cert.Request.ReuseKey {
+		if privateKey, err = l.getInstalledPrivateKey(cert); err != nil {
+			return nil, err
+		}
 	}
 
 	// Execute ACME request
 	request := certificate.ObtainRequest{
-		Domains: domains,
-		Bundle:  false,
+		Domains:        domains,
+		Bundle:         false,
+		PrivateKey:     privateKey,
+		PreferredChain: cert.Request.PreferredChain,
 	}
 
-	var certificates *certificate.Resource
 	certificates, err = client.Certificate.Obtain(request)
 	if err != nil {
 		slog.Debug("could not obtain certificate", "error", err)
 		return nil, fmt.Errorf("could not obtain certificate with message %w", err)
 	}
+	return certificates, nil
+}
 
-	block, _ := pem.Decode(certificates.Certificate)
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse PEM block containing the public key for certificate %s", cert.Name)
+func (l Launcher) obtainForCsr(client *lego.Client, cert config.Certificate) (*certificate.Resource, error) {
+	var (
+		err          error
+		csr          *x509.CertificateRequest
+		certificates *certificate.Resource
+	)
+
+	if csr, err = cert.Request.GetCsr(); err != nil {
+		slog.Debug("invalid csr in request", "certificate", cert.Name, "error", err)
+		return nil, fmt.Errorf("invalid csr in request for certificate %s with message: %w", cert.Name, err)
 	}
-	var pub *x509.Certificate
-	pub, err = x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse DER encoded public key for certificate %s with message %w: ", cert.Name, err)
+
+	// Execute ACME request
+	request := certificate.ObtainForCSRRequest{
+		CSR:            csr,
+		Bundle:         false,
+		PreferredChain: cert.Request.PreferredChain,
 	}
-	slog.Debug("certificate information", "cn", pub.Subject.CommonName, "SAN", pub.DNSNames)
 
+	certificates, err = client.Certificate.ObtainForCSR(request)
+	if err != nil {
+		slog.Debug("could not obtain certificate for csr", "error", err)
+		return nil, fmt.Errorf("could not obtain certificate for csr with message %w", err)
+	}
 	return certificates, nil
 }
 
-func (l Launcher) getCertificateFilename(name string) string {
-	return name + "_" + l.timestamp + ".cer"
-}
+// getInstalledPrivateKey downloads the private key of the certificate currently installed on the first installation target
+// If the certificate is not installed yet, no private key is returned and a new private key will be generated
+func (l Launcher) getInstalledPrivateKey(cert config.Certificate) (crypto.PrivateKey, error) {
+	var (
+		err     error
+		client  *nitro.Client
+		certKey *nitroConfig.SslCertKey
+		content []byte
+	)
+
+	if len(cert.Installation) == 0 {
+		slog.Debug("no installation target to retrieve private key from", "certificate", cert.Name)
+		return nil, nil
+	}
+	t := cert.Installation[0].Target
 
-func (l Launcher) getPrivateKeyFilename(name string) string {
-	return name + "_" + l.timestamp + ".key"
+	slog.Info("retrieve installed private key from target", "target", t, "certificate", cert.Name)
+	if client, certKey, err = l.getInstalledCertKey(cert.Installation[0], cert.Name, []string{"certkey", "key"}); err != nil {
+		return nil, err
+	}
+	if certKey == nil {
+		slog.Debug("certificate not installed on target, generating new private key", "target", t, "certificate", cert.Name)
+		return nil, nil
+	}
+	if certKey.Key == "" {
+		return nil, fmt.Errorf("could not find private key for certificate %s in organization %s environment %s", cert.Name, t.Organization, t.Environment)
+	}
+
+	if content, err = l.downloadFile(client, t, certKey.Key); err != nil {
+		return nil, err
+	}
+	return certcrypto.ParsePEMPrivateKey(content)
 }
 
-func (l Launcher) getSslCertKeyName(name string) string {
-	return "LENS_" + name
+// getInstalledCertificate downloads the PEM encoded certificate currently installed on any of the installation targets
+func (l Launcher) getInstalledCertificate(cert config.Certificate) ([]byte, error) {
+	var (
+		err     error
+		client  *nitro.Client
+		certKey *nitroConfig.SslCertKey
+	)
+
+	for _, i := range cert.Installation {
+		slog.Info("retrieve installed certificate from target", "target", i.Target, "certificate", cert.Name)
+		if client, certKey, err = l.getInstalledCertKey(i, cert.Name, []string{"certkey", "cert"}); err != nil {
+			return nil, err
+		}
+		if certKey == nil || certKey.Cert == "" {
+			slog.Debug("certificate not installed on target", "target", i.Target, "certificate", cert.Name)
+			continue
+		}
+		return l.downloadFile(client, i.Target, certKey.Cert)
+	}
+	return nil, fmt.Errorf("could not find installed certificate %s on any installation target", cert.Name)
 }
 
-func (l Launcher) uploadCertificates(c *nitro.Client, t config.Target, name string, cert *certificate.Resource) error {
+// getInstalledCertificateChain returns the certificate currently installed on the first installation target, together with its linked issuer
+// If the certificate is not installed, or is not linked to an issuer, no certificates are returned
+func (l Launcher) getInstalledCertificateChain(cert config.Certificate) (*x509.Certificate, *x509.Certificate, error) {
 	var (
-		err error
+		err     error
+		client  *nitro.Client
+		certKey *nitroConfig.SslCertKey
+		leaf    []byte
+		issuer  []byte
 	)
-	slog.Info("upload certificate files to target", "target", t, "certificate", name)
-	controller := controllers.NewSystemFileController(c)
 
-	slog.Debug("uploading certificate public key to target", "target", t, "certificate", name)
-	_, err = controller.Add(l.getCertificateFilename(name), LENS_CERTIFICATE_PATH, cert.Certificate)
-	if err != nil {
-		return fmt.Errorf("could not upload certificate public key to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	if len(cert.Installation) == 0 {
+		return nil, nil, nil
 	}
+	t := cert.Installation[0].Target
 
-	slog.Debug("uploading certificate private key to target", "target", t, "certificate", name)
-	_, err = controller.Add(l.getPrivateKeyFilename(name), LENS_CERTIFICATE_PATH, cert.PrivateKey)
-	if err != nil {
-		return fmt.Errorf("could not upload certificate private key to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	if client, certKey, err = l.getInstalledCertKey(cert.Installation[0], cert.Name, []string{"certkey", "cert", "linkcertkeyname"}); err != nil {
+		return nil, nil, err
 	}
-	return nil
+	if certKey == nil || certKey.Cert == "" || certKey.LinkCertKeyName == "" {
+		slog.Debug("no installed certificate chain found on target", "target", t, "certificate", cert.Name)
+		return nil, nil, nil
+	}
+
+	if leaf, err = l.downloadFile(client, t, certKey.Cert); err != nil {
+		return nil, nil, err
+	}
+
+	var res *nitro.Response[nitroConfig.SslCertKey]
+	if res, err = controllers.NewSslCertKeyController(client).Get(certKey.LinkCertKeyName, []string{"certkey", "cert"}); err != nil {
+		return nil, nil, fmt.Errorf("could not get issuer certificate %s from organization %s environment %s with message %w", certKey.LinkCertKeyName, t.Organization, t.Environment, err)
+	}
+	if len(res.Data) == 0 || res.Data[0].Cert == "" {
+		return nil, nil, nil
+	}
+
+	if issuer, err = l.downloadFile(client, t, res.Data[0].Cert); err != nil {
+		return nil, nil, err
+	}
+
+	var leafCert, issuerCert *x509.Certificate
+	if leafCert, err = certcrypto.ParsePEMCertificate(leaf); err != nil {
+		return nil, nil, err
+	}
+	if issuerCert, err = certcrypto.ParsePEMCertificate(issuer); err != nil {
+		return nil, nil, err
+	}
+	return leafCert, issuerCert, nil
 }
 
-func (l Launcher) configureSslCertKey(c *nitro.Client, name string, t config.Target) error {
+// getInstalledCertKey returns the certkey for the certificate on the installation target, or nil if it does not exist
+func (l Launcher) getInstalledCertKey(i config.Installation, name string, attributes []string) (*nitro.Client, *nitroConfig.SslCertKey, error) {
 	var (
 		err       error
 		unwrapErr error
+		client    *nitro.Client
+		res       *nitro.Response[nitroConfig.SslCertKey]
+		t         = i.Target
 	)
-	slog.Info("configure ssl certkey on target", "target", t, "certificate", name)
 
-	controller := controllers.NewSslCertKeyController(c)
+	if client, err = l.getNitroClient(t); err != nil {
+		return nil, nil, err
+	}
 
-	// Check if certificate exists
-	if _, err = controller.Get(l.getSslCertKeyName(name), nil); err != nil {
+	if res, err = controllers.NewSslCertKeyController(client).Get(l.getSslCertKeyName(i, name), attributes); err != nil {
 		unwrapErr = errors.Unwrap(err)
-		if !errors.Is(unwrapErr, nitro.NSERR_SSL_NOCERT) {
-			slog.Debug("could not verify if certificate exists on target", "target", t, "certificate", name, "error", err)
-			return fmt.Errorf("could not verify if certificate exists in organization %s environment %s with message %w", t.Organization, t.Environment, err)
-		} else {
-			slog.Debug("creating ssl certkey on target", "target", t, "certificate", name)
-			if _, err = controller.Add(l.getSslCertKeyName(name), LENS_CERTIFICATE_PATH+l.getCertificateFilename(name), LENS_CERTIFICATE_PATH+l.getPrivateKeyFilename(name)); err != nil {
-				slog.Debug("could not add certificate to environment", "target", t, "certificate", name, "error", err)
-				return fmt.Errorf("could not add certificate to organization %s environment %s with message %w", t.Organization, t.Environment, err)
-			}
+		if errors.Is(unwrapErr, nitro.NSERR_SSL_NOCERT) {
+			return client, nil, nil
 		}
-	} else {
-		slog.Debug("updating ssl certkey on target", "target", t, "certificate", name)
-		if _, err = controller.Update(l.getSslCertKeyName(name), LENS_CERTIFICATE_PATH+l.getCertificateFilename(name), LENS_CERTIFICATE_PATH+l.getPrivateKeyFilename(name), true); err != nil {
-			slog.Debug("could not update certificate exists in environment", "target", t, "certificate", name, "error", err)
-			return fmt.Errorf("could not update certificate in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+		slog.Debug("could not verify if certificate exists on target", "target", t, "certificate", name, "error", err)
+		return nil, nil, fmt.Errorf("could not verify if certificate exists in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	if len(res.Data) == 0 {
+		return client, nil, nil
+	}
+	return client, &res.Data[0], nil
+}
 
-		}
+// downloadFile downloads a file from the target
+// Relative paths on NetScaler ADC are relative to the default certificate directory
+func (l Launcher) downloadFile(c *nitro.Client, t config.Target, filePath string) ([]byte, error) {
+	var (
+		err     error
+		file    *nitro.Response[nitroConfig.SystemFile]
+		content []byte
+	)
+
+	if !path.IsAbs(filePath) {
+		filePath = path.Join("/nsconfig/ssl", filePath)
 	}
 
-	return nil
+	slog.Debug("downloading file from target", "target", t, "file", filePath)
+	if file, err = controllers.NewSystemFileController(c).Get(path.Base(filePath), path.Dir(filePath)); err != nil {
+		return nil, fmt.Errorf("could not download file %s from organization %s environment %s with message %w", filePath, t.Organization, t.Environment, err)
+	}
+	if len(file.Data) == 0 {
+		return nil, fmt.Errorf("could not find file %s in organization %s environment %s", filePath, t.Organization, t.Environment)
+	}
+
+	if content, err = base64.StdEncoding.DecodeString(file.Data[0].FileContent); err != nil {
+		return nil, fmt.Errorf("could not decode file %s with message %w", filePath, err)
+	}
+	return content, nil
 }
 
-func (l Launcher) configureCertificates(c *nitro.Client, i config.Installation, name string) error {
+// revokeCertificate revokes the installed certificate using the ACME account of the user configured for the request
+func (l Launcher) revokeCertificate(cert config.Certificate, reason uint) error {
 	var (
-		err error
+		err     error
+		client  *lego.Client
+		content []byte
 	)
+	slog.Info("revoke certificate", "certificate", cert.Name, "reason", reason)
 
-	err = l.configureSslCertKey(c, name, i.Target)
-	if err != nil {
+	if content, err = l.getInstalledCertificate(cert); err != nil {
 		return err
 	}
+	if _, err = certcrypto.ParsePEMCertificate(content); err != nil {
+		return fmt.Errorf("could not parse installed certificate %s with message %w", cert.Name, err)
+	}
 
-	if len(i.SslVirtualServers) > 0 {
-		err = l.bindSslVservers(c, name, i)
-		if err != nil {
-			return err
-		}
+	client, err = l.getLegoClient(cert.Request.User, cert.Request.GetServiceUrl(), cert.Request.GetKeyType())
+	if err != nil {
+		return err
 	}
 
-	if len(i.SslServices) > 0 {
-		err = l.bindSslService(c, name, i)
-		if err != nil {
-			return err
-		}
+	if err = client.Certificate.RevokeWithReason(content, &reason); err != nil {
+		slog.Debug("could not revoke certificate", "certificate", cert.Name, "error", err)
+		return fmt.Errorf("could not revoke certificate %s with message %w", cert.Name, err)
 	}
+	slog.Info("certificate revoked", "certificate", cert.Name)
 	return nil
 }
 
-func (l Launcher) updateEnvironment(i config.Installation, name string, cert *certificate.Resource) error {
+// removeCertificate unbinds the certificate from all endpoints it is bound to and removes the certkey from the target
+func (l Launcher) removeCertificate(i config.Installation, name string) error {
 	var (
-		err    error
-		e      registry.Environment
-		client *nitro.Client
+		err     error
+		client  *nitro.Client
+		certKey *nitroConfig.SslCertKey
 	)
-	slog.Info("install certificate on target", "target", i.Target, "certificate", name)
+	slog.Info("remove certificate from target", "target", i.Target, "certificate", name)
 
-	e, err = l.getEnvironment(i.Target)
-	if err != nil {
-		slog.Debug("could not get environment for organization", "target", i.Target, "certificate", name)
-		l.errorChannel <- fmt.Errorf("could not get environment %s for organization %s with message %w", i.Target.Environment, i.Target.Organization, err)
+	if client, certKey, err = l.getInstalledCertKey(i, name, []string{"certkey"}); err != nil {
+		return err
+	}
+	if certKey == nil {
+		slog.Debug("certificate not installed on target", "target", i.Target, "certificate", name)
+		return nil
 	}
 
-	client, err = e.GetPrimaryNitroClient()
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(client)
 
-	err = l.uploadCertificates(client, i.Target, name, cert)
-	if err != nil {
+	if err = l.unbindCertificate(client, name, i); err != nil {
 		return err
 	}
 
-	if i.ReplaceDefaultCertificate {
-		err = l.replaceDefaultCertificate(client, i.Target, LENS_CERTIFICATE_PATH+l.getCertificateFilename(name), LENS_CERTIFICATE_PATH+l.getPrivateKeyFilename(name))
-		if err != nil {
-			slog.Debug("could not replace default certificate", "target", i.Target)
-			return err
-		}
-	} else {
-		err = l.configureCertificates(client, i, name)
-		if err != nil {
-			//
-			return err
-		}
+	slog.Debug("remove ssl certkey from target", "target", i.Target, "certificate", name)
+	if _, err = controller.Delete(certKeyName); err != nil {
+		return fmt.Errorf("could not remove certificate %s from organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
 	}
 
 	slog.Info("saving config on target", "target", i.Target)
@@ -572,109 +887,1057 @@ func (l Launcher) updateEnvironment(i config.Installation, name string, cert *ce
 		slog.Debug("error saving config", "target", i.Target, "error", err)
 		return err
 	}
-	slog.Info("process complete", "target", i.Target, "certificate", name)
 	return nil
 }
 
-func (l Launcher) replaceDefaultCertificate(c *nitro.Client, t config.Target, certFilename string, keyFilename string) error {
-	var (
-		err error
-	)
-	slog.Info("replacing default certificate on target", "target", t)
-	controller := controllers.NewSslCertKeyController(c)
-	_, err = controller.Update("ns-server-certificate", certFilename, keyFilename, true)
-	return err
+// unbindCertificate removes every binding of the certkey, including bindings to endpoints not defined in the installation
+func (l Launcher) unbindCertificate(c *nitro.Client, name string, i config.Installation) error {
+	var err error
+
+	// Pruning against an installation without endpoints unbinds the certkey from all endpoints
+	unbound := i
+	unbound.SslVirtualServers = nil
+	unbound.SslServices = nil
+	unbound.SslServiceGroups = nil
+	unbound.SslProfiles = nil
+	for _, prune := range []func(*nitro.Client, string, config.Installation) error{l.pruneSslVserverBindings, l.pruneSslServiceBindings, l.pruneSslServiceGroupBindings, l.pruneSslProfileBindings} {
+		if err = prune(c, name, unbound); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (l Launcher) bindSslVservers(c *nitro.Client, name string, i config.Installation) error {
-	var (
-		err error
-	)
-	slog.Info("bind certificate to ssl vservers", "target", i.Target)
-	certKeyName := l.getSslCertKeyName(name)
-	controller := controllers.NewSslCertKeyController(c)
+// publicKeyEqual reports whether both public keys are the same
+func (l Launcher) publicKeyEqual(a crypto.PublicKey, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
 
-	var bindings *nitro.Response[nitroConfig.SslCertKeySslVserverBinding]
-	if bindings, err = controller.GetSslVserverBinding(certKeyName, nil); err != nil {
-		slog.Debug("could not verify if certificate exists", "target", i.Target, "certificate", name, "error", err)
-		return fmt.Errorf("could not verify if certificate exists in organization %s environment %s with message %w", i.Target.Organization, i.Target.Environment, err)
-	}
-	if len(bindings.Data) == 0 {
-		for _, bindTo := range i.SslVirtualServers {
-			slog.Debug("bind certificate to ssl vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name)
-			if _, err = controller.BindSslVserver(bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
-				// TODO SEND TO ERROR CHANNEL
-				slog.Error("could not bind certificate to vserver", "target", i.Target, "certificate", name, "error", err)
-				// return fmt.Errorf("could not bind certificate %s to vserver in environment %s with message %w", certKeyName, e.Name, err)
-			}
-		}
-	} else {
-		// TODO UPDATE FLOW --> check if vserver name in SslVirtualServers exists before trying to bind
-		slog.Debug("found existing bindings for certificate", "target", i.Target, "certificate", name, "count", len(bindings.Data))
-		for _, bindTo := range i.SslVirtualServers {
-			for _, boundTo := range bindings.Data {
-				if bindTo.Name == boundTo.ServerName {
-					slog.Debug("certificate already bound to vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name)
-					continue
-				} else {
-					slog.Debug("binding certificate to vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name)
-					if _, err = controller.BindSslVserver(bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
-						slog.Debug("could not bind certificate to vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name, "error", err)
-						// return fmt.Errorf("could not bind certificate %s to vserver in environment %s with message %w", certKeyName, e.Name, err)
-						// TODO WHY NO RETURN?
-					}
-				}
-			}
-		}
+func (l Launcher) getCertificateFilename(i config.Installation, name string) string {
+	return i.GetFilename(name, l.timestamp) + ".cer"
+}
+
+func (l Launcher) getPrivateKeyFilename(i config.Installation, name string) string {
+	return i.GetFilename(name, l.timestamp) + ".key"
+}
+
+// getCertificateDirectory returns the directory on the target to which the certificate files are uploaded
+func (l Launcher) getCertificateDirectory(i config.Installation) string {
+	if i.Directory == "" {
+		return LENS_CERTIFICATE_PATH
 	}
-	return err
+	return strings.TrimSuffix(i.Directory, "/") + "/"
 }
 
-func (l Launcher) bindSslService(c *nitro.Client, name string, i config.Installation) error {
+func (l Launcher) getCertificatePath(i config.Installation, name string) string {
+	return l.getCertificateDirectory(i) + l.getCertificateFilename(i, name)
+}
+
+// getPrivateKeyPath returns the path of the private key on the target
+// When a certificate is requested using a CSR, the private key already exists on the target and is not uploaded by lens
+func (l Launcher) getPrivateKeyPath(i config.Installation, r config.Certificate) string {
+	if r.Request.CsrKeyFile != "" {
+		return r.Request.CsrKeyFile
+	}
+	return l.getCertificateDirectory(i) + l.getPrivateKeyFilename(i, r.Name)
+}
+
+// getSslCertKeyName returns the certkey name configured for the installation, or LENS_<name> if none is set
+func (l Launcher) getSslCertKeyName(i config.Installation, name string) string {
+	if i.CertKeyName != "" {
+		return i.CertKeyName
+	}
+	return "LENS_" + name
+}
+
+func (l Launcher) getIssuerFilename(certKeyName string) string {
+	return certKeyName + ".cer"
+}
+
+// getIssuerCertKeyName derives the certkey name for an issuer certificate from its fingerprint,
+// so the same intermediate is only installed once per target, regardless of the number of certificates it signed
+func (l Launcher) getIssuerCertKeyName(fingerprint string) string {
+	return "LENS_CA_" + fingerprint[:20]
+}
+
+// getIssuerChain splits the issuer certificates returned by the ACME service into separate PEM encoded certificates,
+// ordered from the certificate which signed the leaf certificate up to the top of the chain
+func (l Launcher) getIssuerChain(cert *certificate.Resource) ([][]byte, error) {
+	var (
+		block  *pem.Block
+		rest   = cert.IssuerCertificate
+		output [][]byte
+	)
+
+	for {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		output = append(output, pem.EncodeToMemory(block))
+	}
+
+	if len(output) == 0 {
+		return nil, fmt.Errorf("failed to parse PEM block containing the issuer certificate")
+	}
+	return output, nil
+}
+
+func (l Launcher) getIssuerFingerprint(issuer []byte) (string, error) {
+	block, _ := pem.Decode(issuer)
+	if block == nil {
+		return "", fmt.Errorf("failed to parse PEM block containing the issuer certificate")
+	}
+	fingerprint := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(fingerprint[:]), nil
+}
+
+func (l Launcher) uploadCertificates(c *nitro.Client, i config.Installation, name string, cert *certificate.Resource) error {
+	var (
+		err       error
+		t         = i.Target
+		directory = l.getCertificateDirectory(i)
+	)
+	slog.Info("upload certificate files to target", "target", t, "certificate", name)
+	controller := controllers.NewSystemFileController(c)
+
+	slog.Debug("uploading certificate public key to target", "target", t, "certificate", name)
+	_, err = controller.Add(l.getCertificateFilename(i, name), directory, cert.Certificate)
+	if err != nil {
+		return fmt.Errorf("could not upload certificate public key to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+
+	// Certificates requested using a CSR do not have a private key, as it is already present on the target
+	if len(cert.PrivateKey) == 0 {
+		slog.Debug("no private key to upload to target", "target", t, "certificate", name)
+		return nil
+	}
+
+	slog.Debug("uploading certificate private key to target", "target", t, "certificate", name)
+	_, err = controller.Add(l.getPrivateKeyFilename(i, name), directory, cert.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("could not upload certificate private key to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	return nil
+}
+
+// configureIssuerCertKeys installs every certificate of the issuer chain as a separate certkey and links them in order.
+// The name of the certkey which must be linked to the leaf certificate is returned.
+func (l Launcher) configureIssuerCertKeys(c *nitro.Client, i config.Installation, name string, cert *certificate.Resource) (string, error) {
+	var (
+		err          error
+		chain        [][]byte
+		certKeyNames []string
+		existing     map[string]string
+		t            = i.Target
+	)
+	slog.Info("configure issuer certkeys on target", "target", t, "certificate", name)
+
+	chain, err = l.getIssuerChain(cert)
+	if err != nil {
+		slog.Debug("could not get issuer certificate chain", "target", t, "certificate", name, "error", err)
+		return "", fmt.Errorf("could not get issuer certificate chain for certificate %s with message %w", name, err)
+	}
+
+	if existing, err = l.getCaCertKeyFingerprints(c, t); err != nil {
+		return "", err
+	}
+
+	for _, issuer := range chain {
+		var issuerCertKeyName string
+		issuerCertKeyName, err = l.configureIssuerCertKey(c, i, name, issuer, existing)
+		if err != nil {
+			return "", err
+		}
+		certKeyNames = append(certKeyNames, issuerCertKeyName)
+	}
+
+	// Link every intermediate certificate to the certificate which signed it
+	for k := 0; k < len(certKeyNames)-1; k++ {
+		err = l.linkIssuerCertKey(c, t, certKeyNames[k], certKeyNames[k+1])
+		if err != nil {
+			return "", err
+		}
+	}
+	return certKeyNames[0], nil
+}
+
+// configureIssuerCertKey returns the certkey for the issuer certificate, creating LENS_CA_<fingerprint> on the target
+// when no certkey with the same fingerprint exists yet, so an intermediate which is already installed under another name is reused
+func (l Launcher) configureIssuerCertKey(c *nitro.Client, i config.Installation, name string, issuer []byte, existing map[string]string) (string, error) {
+	var (
+		err         error
+		fingerprint string
+		t           = i.Target
+		directory   = l.getCertificateDirectory(i)
+	)
+
+	fingerprint, err = l.getIssuerFingerprint(issuer)
+	if err != nil {
+		slog.Debug("could not get issuer certificate fingerprint", "target", t, "certificate", name, "error", err)
+		return "", fmt.Errorf("could not get issuer certificate fingerprint for certificate %s with message %w", name, err)
+	}
+
+	if certKeyName, found := existing[fingerprint]; found {
+		slog.Debug("found existing issuer certkey on target", "target", t, "certificate", name, "issuer", certKeyName)
+		return certKeyName, nil
+	}
+	issuerCertKeyName := l.getIssuerCertKeyName(fingerprint)
+	controller := controllers.NewSslCertKeyController(c)
+
+	slog.Debug("uploading issuer certificate to target", "target", t, "certificate", name, "issuer", issuerCertKeyName)
+	fileController := controllers.NewSystemFileController(c)
+	if _, err = fileController.Add(l.getIssuerFilename(issuerCertKeyName), directory, issuer); err != nil {
+		return "", fmt.Errorf("could not upload issuer certificate to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+
+	slog.Debug("creating issuer certkey on target", "target", t, "certificate", name, "issuer", issuerCertKeyName)
+	if _, err = controller.Add(issuerCertKeyName, directory+l.getIssuerFilename(issuerCertKeyName), ""); err != nil {
+		slog.Debug("could not add issuer certificate to environment", "target", t, "certificate", name, "issuer", issuerCertKeyName, "error", err)
+		return "", fmt.Errorf("could not add issuer certificate to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	existing[fingerprint] = issuerCertKeyName
+	return issuerCertKeyName, nil
+}
+
+// getCaCertKeyFingerprints returns the names of the certkeys without private key on the target, indexed by the fingerprint of their certificate
+// Certificates which cannot be downloaded or parsed are skipped, as they cannot be matched to an issuer certificate
+func (l Launcher) getCaCertKeyFingerprints(c *nitro.Client, t config.Target) (map[string]string, error) {
+	var (
+		err    error
+		res    *nitro.Response[nitroConfig.SslCertKey]
+		output = make(map[string]string)
+	)
+
+	nitroRequest := &nitro.Request[nitroConfig.SslCertKey]{
+		Attributes: []string{"certkey", "cert", "key"},
+	}
+	if res, err = nitro.ExecuteNitroRequest[nitroConfig.SslCertKey](c, nitroRequest); err != nil {
+		slog.Debug("could not list certificates", "target", t, "error", err)
+		return nil, fmt.Errorf("could not list certificates in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+
+	for _, certKey := range res.Data {
+		var (
+			content []byte
+			cert    *x509.Certificate
+		)
+
+		if certKey.Key != "" || certKey.Cert == "" {
+			continue
+		}
+		if content, err = l.downloadFile(c, t, certKey.Cert); err != nil {
+			slog.Debug("skipping ca certificate", "target", t, "certkey", certKey.CertKey, "error", err)
+			continue
+		}
+		// Certificates on NetScaler ADC are stored in PEM or DER format
+		if cert, err = certcrypto.ParsePEMCertificate(content); err != nil {
+			if cert, err = x509.ParseCertificate(content); err != nil {
+				slog.Debug("skipping ca certificate", "target", t, "certkey", certKey.CertKey, "error", err)
+				continue
+			}
+		}
+
+		fingerprint := sha256.Sum256(cert.Raw)
+		// The certkey named after the fingerprint is preferred when the certificate is installed more than once
+		key := hex.EncodeToString(fingerprint[:])
+		if _, found := output[key]; !found || certKey.CertKey == l.getIssuerCertKeyName(key) {
+			output[key] = certKey.CertKey
+		}
+	}
+	return output, nil
+}
+
+// linkIssuerCertKey links an issuer certkey to the certkey of the certificate which signed it, unless the link already exists
+func (l Launcher) linkIssuerCertKey(c *nitro.Client, t config.Target, certKeyName string, issuerCertKeyName string) error {
 	var (
 		err error
+		res *nitro.Response[nitroConfig.SslCertKey]
 	)
-	slog.Info("bind certificate to ssl services", "target", i.Target)
-	certKeyName := l.getSslCertKeyName(name)
 	controller := controllers.NewSslCertKeyController(c)
 
-	var bindings *nitro.Response[nitroConfig.SslCertKeyServiceBinding]
-	if bindings, err = controller.GetServiceBinding(certKeyName, nil); err != nil {
-		slog.Debug("could not verify if certificate exists on target", "target", i.Target, "certificate", name, "error", err)
-		return fmt.Errorf("could not verify if certificate exists in organization %s environment %s with message %w", i.Target.Organization, i.Target.Environment, err)
-	}
-	if len(bindings.Data) == 0 {
-		for _, bindTo := range i.SslServices {
-			slog.Debug("bind certificate to ssl service", "target", i.Target, "certificate", name, "service", bindTo.Name)
-			if _, err = controller.BindSslService(bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
-				// TODO SEND TO ERROR CHANNEL
-				slog.Error("could not bind certificate to ssl service", "organization", i.Target.Organization, "environment", i.Target.Environment, "certificate", certKeyName, "error", err)
-				// return fmt.Errorf("could not bind certificate %s to service in environment %s with message %w", certKeyName, e.Name, err)
+	if res, err = controller.Get(certKeyName, []string{"certkey", "linkcertkeyname"}); err != nil {
+		slog.Debug("could not verify issuer certkey link on target", "target", t, "certkey", certKeyName, "error", err)
+		return fmt.Errorf("could not verify link for certificate %s in organization %s environment %s with message %w", certKeyName, t.Organization, t.Environment, err)
+	}
+
+	if len(res.Data) > 0 && res.Data[0].LinkCertKeyName == issuerCertKeyName {
+		slog.Debug("issuer certkey already linked on target", "target", t, "certkey", certKeyName, "issuer", issuerCertKeyName)
+		return nil
+	}
+
+	slog.Debug("linking issuer certkey on target", "target", t, "certkey", certKeyName, "issuer", issuerCertKeyName)
+	if _, err = controller.Link(certKeyName, issuerCertKeyName); err != nil {
+		slog.Debug("could not link issuer certkey", "target", t, "certkey", certKeyName, "issuer", issuerCertKeyName, "error", err)
+		return fmt.Errorf("could not link certificate %s to issuer %s in organization %s environment %s with message %w", certKeyName, issuerCertKeyName, t.Organization, t.Environment, err)
+	}
+	return nil
+}
+
+func (l Launcher) configureSslCertKey(c *nitro.Client, name string, i config.Installation, keyPath string, issuerCertKeyName string) error {
+	var (
+		err         error
+		unwrapErr   error
+		linkedName  string
+		t           = i.Target
+		certKeyName = l.getSslCertKeyName(i, name)
+		certPath    = l.getCertificatePath(i, name)
+	)
+	slog.Info("configure ssl certkey on target", "target", t, "certificate", name)
+
+	controller := controllers.NewSslCertKeyController(c)
+
+	// Check if certificate exists
+	var res *nitro.Response[nitroConfig.SslCertKey]
+	if res, err = controller.Get(certKeyName, []string{"certkey", "linkcertkeyname"}); err != nil {
+		unwrapErr = errors.Unwrap(err)
+		if !errors.Is(unwrapErr, nitro.NSERR_SSL_NOCERT) {
+			slog.Debug("could not verify if certificate exists on target", "target", t, "certificate", name, "error", err)
+			return fmt.Errorf("could not verify if certificate exists in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+		} else {
+			slog.Debug("creating ssl certkey on target", "target", t, "certificate", name)
+			if _, err = controller.Add(certKeyName, certPath, keyPath); err != nil {
+				slog.Debug("could not add certificate to environment", "target", t, "certificate", name, "error", err)
+				return fmt.Errorf("could not add certificate to organization %s environment %s with message %w", t.Organization, t.Environment, err)
 			}
 		}
 	} else {
-		// TODO UPDATE FLOW --> check if service name in SslVirtualServers exists before trying to bind
-		slog.Debug("found existing bindings for certificate", "target", i.Target, "certificate", name, "count", len(bindings.Data))
-		for _, bindTo := range i.SslServices {
-			for _, boundTo := range bindings.Data {
-				if bindTo.Name == boundTo.ServiceName {
-					slog.Debug("certificate already bound to ssl service", "target", i.Target, "certificate", name, "service", bindTo.Name)
-					continue
-				} else {
-					slog.Debug("binding certificate to service", "target", i.Target, "certificate", name, "service", bindTo.Name)
-					if _, err = controller.BindSslService(bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
-						// TODO SEND TO ERROR CHANNEL
-						slog.Error("could not bind certificate to ssl service", "target", i.Target, "certificate", name, "service", bindTo.Name, "error", err)
-						// return fmt.Errorf("could not bind certificate %s to service in environment %s with message %w", certKeyName, e.Name, err)
-						// TODO WHY NO RETURN?
-					}
-				}
+		if len(res.Data) > 0 {
+			linkedName = res.Data[0].LinkCertKeyName
+		}
+
+		// The existing link must be removed when the CA rotated its chain, as the new certificate is no longer signed by the linked issuer
+		if linkedName != "" && linkedName != issuerCertKeyName {
+			slog.Debug("unlinking ssl certkey from previous issuer on target", "target", t, "certificate", name, "issuer", linkedName)
+			if _, err = controller.Unlink(certKeyName); err != nil {
+				slog.Debug("could not unlink certificate from previous issuer", "target", t, "certificate", name, "issuer", linkedName, "error", err)
+				return fmt.Errorf("could not unlink certificate from issuer %s in organization %s environment %s with message %w", linkedName, t.Organization, t.Environment, err)
 			}
 		}
+
+		slog.Debug("updating ssl certkey on target", "target", t, "certificate", name)
+		if _, err = controller.Update(certKeyName, certPath, keyPath, true); err != nil {
+			slog.Debug("could not update certificate exists in environment", "target", t, "certificate", name, "error", err)
+			return fmt.Errorf("could not update certificate in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+
+		}
+	}
+
+	if linkedName == issuerCertKeyName {
+		slog.Debug("ssl certkey already linked to issuer on target", "target", t, "certificate", name, "issuer", issuerCertKeyName)
+		return nil
+	}
+
+	slog.Debug("linking ssl certkey to issuer on target", "target", t, "certificate", name, "issuer", issuerCertKeyName)
+	if _, err = controller.Link(certKeyName, issuerCertKeyName); err != nil {
+		slog.Debug("could not link certificate to issuer", "target", t, "certificate", name, "issuer", issuerCertKeyName, "error", err)
+		return fmt.Errorf("could not link certificate to issuer %s in organization %s environment %s with message %w", issuerCertKeyName, t.Organization, t.Environment, err)
+	}
+
+	return nil
+}
+
+func (l Launcher) configureCertificates(c *nitro.Client, i config.Installation, name string, keyPath string, cert *certificate.Resource) error {
+	var (
+		err               error
+		issuerCertKeyName string
+	)
+
+	issuerCertKeyName, err = l.configureIssuerCertKeys(c, i, name, cert)
+	if err != nil {
+		return err
+	}
+
+	err = l.configureSslCertKey(c, name, i, keyPath, issuerCertKeyName)
+	if err != nil {
+		return err
+	}
+
+	return l.bindCertificate(c, name, i, issuerCertKeyName)
+}
+
+// bindCertificate reconciles the bindings of the certificate with the endpoints of the installation
+// Binding continues for the remaining endpoints when an endpoint fails, all errors are returned joined together.
+// Bindings are only pruned when every endpoint is bound successfully.
+func (l Launcher) bindCertificate(c *nitro.Client, name string, i config.Installation, issuerCertKeyName string) error {
+	var errs []error
+
+	if len(i.SslVirtualServers) > 0 {
+		errs = append(errs, l.bindSslVservers(c, name, i))
+	}
+	if len(i.SslServices) > 0 {
+		errs = append(errs, l.bindSslService(c, name, i))
+	}
+	if len(i.SslServiceGroups) > 0 {
+		errs = append(errs, l.bindSslServiceGroups(c, name, i))
+	}
+	if len(i.SslProfiles) > 0 {
+		errs = append(errs, l.bindSslProfiles(c, name, i))
+	}
+	if len(i.CaCertificates) > 0 {
+		errs = append(errs, l.bindCaCertificates(c, name, i, issuerCertKeyName))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		if i.PruneBindings {
+			slog.Warn("skipping pruning of bindings, as not all bindings succeeded", "target", i.Target, "certificate", name)
+		}
+		return err
+	}
+
+	if !i.PruneBindings {
+		return nil
+	}
+
+	// Only endpoint types defined in the installation are pruned, bindings to endpoint types without any endpoint are left untouched
+	errs = nil
+	if len(i.SslVirtualServers) > 0 {
+		errs = append(errs, l.pruneSslVserverBindings(c, name, i))
 	}
+	if len(i.SslServices) > 0 {
+		errs = append(errs, l.pruneSslServiceBindings(c, name, i))
+	}
+	if len(i.SslServiceGroups) > 0 {
+		errs = append(errs, l.pruneSslServiceGroupBindings(c, name, i))
+	}
+	if len(i.SslProfiles) > 0 {
+		errs = append(errs, l.pruneSslProfileBindings(c, name, i))
+	}
+	return errors.Join(errs...)
+}
+
+func (l Launcher) updateEnvironment(i config.Installation, r config.Certificate) error {
+	var (
+		err     error
+		client  *nitro.Client
+		name    = r.Name
+		cert    = r.Resource
+		keyPath = l.getPrivateKeyPath(i, r)
+	)
+	slog.Info("install certificate on target", "target", i.Target, "certificate", name)
+
+	client, err = l.getNitroClient(i.Target)
+	if err != nil {
+		slog.Debug("could not connect to target", "target", i.Target, "certificate", name, "error", err)
+		return err
+	}
+
+	// The key pair is verified before any file is uploaded, so a mismatching certificate never reaches the target
+	if i.ReplaceDefaultCertificate && i.SafeReplace {
+		if err = l.verifyKeyPair(client, i.Target, cert, keyPath); err != nil {
+			return fmt.Errorf("could not replace default certificate in organization %s environment %s with message %w", i.Target.Organization, i.Target.Environment, err)
+		}
+	}
+
+	err = l.uploadCertificates(client, i, name, cert)
+	if err != nil {
+		return err
+	}
+
+	if i.ReplaceDefaultCertificate {
+		if i.SafeReplace {
+			err = l.safeReplaceDefaultCertificate(client, i, r, keyPath)
+		} else {
+			err = l.replaceDefaultCertificate(client, i.Target, l.getCertificatePath(i, name), keyPath)
+		}
+		if err != nil {
+			slog.Debug("could not replace default certificate", "target", i.Target)
+			return err
+		}
+	} else {
+		err = l.configureCertificates(client, i, name, keyPath, cert)
+
+		// The certificate is installed when only bindings failed, so the remaining vservers are verified and the configuration is saved
+		if err != nil && !onlyBindingErrors(err) {
+			return err
+		}
+	}
+
+	if err = l.saveInstallation(client, i, r, err); err != nil {
+		return err
+	}
+	slog.Info("process complete", "target", i.Target, "certificate", name)
+	return nil
+}
+
+// saveInstallation verifies the virtual servers of the installation and saves the running configuration on the target
+// Virtual servers the certificate could not be bound to are not verified, the binding errors in installErr are returned after saving.
+// When verification fails, the certificate remains installed in the running configuration, which is not saved.
+func (l Launcher) saveInstallation(c *nitro.Client, i config.Installation, r config.Certificate, installErr error) error {
+	var (
+		err error
+	)
+
+	if err = l.verifyInstallation(i, r, getFailedEndpoints(installErr, BINDING_ENDPOINT_VSERVER)); err != nil {
+		slog.Error("certificate is installed in the running configuration but the configuration is not saved", "target", i.Target, "certificate", r.Name)
+		return errors.Join(installErr, fmt.Errorf("certificate %s is installed in the running configuration of organization %s environment %s, the configuration is not saved with message %w", r.Name, i.Target.Organization, i.Target.Environment, err))
+	}
+
+	slog.Info("saving config on target", "target", i.Target)
+	if err = c.SaveConfig(); err != nil {
+		slog.Debug("error saving config", "target", i.Target, "error", err)
+		return errors.Join(installErr, err)
+	}
+	return installErr
+}
+
+func (l Launcher) replaceDefaultCertificate(c *nitro.Client, t config.Target, certFilename string, keyFilename string) error {
+	var (
+		err error
+	)
+	slog.Info("replacing default certificate on target", "target", t)
+	controller := controllers.NewSslCertKeyController(c)
+	_, err = controller.Update(LENS_DEFAULT_CERTKEY_NAME, certFilename, keyFilename, true)
 	return err
 }
 
+// safeReplaceDefaultCertificate replaces the default certificate, the key pair is verified before the files are uploaded
+// The previous certificate files are restored when a new NITRO session over HTTPS cannot be established with the new certificate,
+// using the connection settings of the environment, or plain HTTP when revertOverHttp is set for the installation
+func (l Launcher) safeReplaceDefaultCertificate(c *nitro.Client, i config.Installation, r config.Certificate, keyPath string) error {
+	var (
+		err          error
+		revertErr    error
+		res          *nitro.Response[nitroConfig.SslCertKey]
+		previous     nitroConfig.SslCertKey
+		revertClient *nitro.Client
+		t            = i.Target
+	)
+
+	controller := controllers.NewSslCertKeyController(c)
+	if res, err = controller.Get(LENS_DEFAULT_CERTKEY_NAME, []string{"certkey", "cert", "key"}); err != nil {
+		return fmt.Errorf("could not get default certificate in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	if len(res.Data) == 0 {
+		return fmt.Errorf("could not find default certificate in organization %s environment %s", t.Organization, t.Environment)
+	}
+	previous = res.Data[0]
+	slog.Debug("current default certificate", "target", t, "cert", previous.Cert, "key", previous.Key)
+
+	if err = l.replaceDefaultCertificate(c, t, l.getCertificatePath(i, r.Name), keyPath); err != nil {
+		return err
+	}
+
+	if err = l.verifyManagementConnection(t); err != nil {
+		slog.Error("could not connect to target after replacing default certificate, reverting", "target", t, "cert", previous.Cert, "key", previous.Key, "error", err)
+
+		// A new session is required, as the existing client may connect over HTTPS, which is not guaranteed to work with the new certificate
+		// Plain HTTP is only used when explicitly enabled for the installation, as it sends the credentials unencrypted
+		if i.RevertOverHttp {
+			slog.Warn("reverting default certificate over plain http", "target", t)
+			revertClient, revertErr = l.getManagementClient(t, false)
+		} else {
+			revertClient, revertErr = l.getNitroClient(t)
+		}
+		if revertErr == nil {
+			revertErr = l.replaceDefaultCertificate(revertClient, t, previous.Cert, previous.Key)
+		}
+		if revertErr != nil {
+			return fmt.Errorf("could not revert default certificate in organization %s environment %s with message %w", t.Organization, t.Environment, errors.Join(err, revertErr))
+		}
+		return fmt.Errorf("reverted default certificate in organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	slog.Info("verified management connection with new default certificate", "target", t)
+	return nil
+}
+
+// verifyKeyPair checks that the private key matches the certificate before it is installed
+// Certificates requested using a CSR do not have a private key, so the key is downloaded from the target instead
+func (l Launcher) verifyKeyPair(c *nitro.Client, t config.Target, cert *certificate.Resource, keyPath string) error {
+	var (
+		err error
+		key = cert.PrivateKey
+	)
+
+	if len(key) == 0 {
+		if key, err = l.downloadFile(c, t, keyPath); err != nil {
+			return err
+		}
+	}
+	if _, err = tls.X509KeyPair(cert.Certificate, key); err != nil {
+		return fmt.Errorf("private key %s does not match certificate with message %w", keyPath, err)
+	}
+	return nil
+}
+
+// verifyManagementConnection logs in to the target with a new NITRO session over HTTPS,
+// so the management interface is verified to work with the current default certificate
+func (l Launcher) verifyManagementConnection(t config.Target) error {
+	var (
+		err    error
+		client *nitro.Client
+	)
+
+	if client, err = l.getManagementClient(t, true); err != nil {
+		return fmt.Errorf("could not connect over https with message %w", err)
+	}
+	if err = client.Login(); err != nil {
+		return fmt.Errorf("could not login over https with message %w", err)
+	}
+	return nil
+}
+
+// getManagementClient returns a new client for the primary node of the target environment, using HTTPS when useSsl is set,
+// regardless of the connection settings of the environment
+func (l Launcher) getManagementClient(t config.Target, useSsl bool) (*nitro.Client, error) {
+	var (
+		err         error
+		environment registry.Environment
+		client      *nitro.Client
+	)
+
+	if environment, err = l.getEnvironment(t); err != nil {
+		return nil, err
+	}
+	environment.Settings.UseSsl = useSsl
+
+	if client, err = environment.GetPrimaryNitroClient(); err != nil {
+		return nil, err
+	}
+	if err = netscaleradc.SwitchPartition(client, t.Partition); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// bindSslVservers binds the certificate to the ssl vservers of the installation to which it is not bound yet
+// Binding continues when an ssl vserver fails, every failed binding is returned as BindingError
+func (l Launcher) bindSslVservers(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		errs     []error
+		bindings *nitro.Response[nitroConfig.SslCertKeySslVserverBinding]
+		bound    = make(map[string]bool)
+	)
+	slog.Info("bind certificate to ssl vservers", "target", i.Target)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	if bindings, err = controller.GetSslVserverBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl vserver bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get vserver bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+	for _, boundTo := range bindings.Data {
+		bound[boundTo.ServerName] = true
+	}
+	slog.Debug("found existing bindings for certificate", "target", i.Target, "certificate", name, "count", len(bound))
+
+	for _, bindTo := range i.SslVirtualServers {
+		if bound[bindTo.Name] {
+			slog.Debug("certificate already bound to vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name)
+			continue
+		}
+
+		if err = l.verifySslVserver(c, i.Target, bindTo.Name, bindTo.Type); err == nil {
+			slog.Debug("bind certificate to ssl vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name)
+			err = l.bindServerCertificate(c, BINDING_ENDPOINT_VSERVER, bindTo.Name, certKeyName, bindTo.SniEnabled)
+		}
+		if err != nil {
+			slog.Error("could not bind certificate to vserver", "target", i.Target, "certificate", name, "vserver", bindTo.Name, "error", err)
+			errs = append(errs, BindingError{Target: i.Target, CertKeyName: certKeyName, Endpoint: BINDING_ENDPOINT_VSERVER, Name: bindTo.Name, Err: err})
+			continue
+		}
+		bound[bindTo.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// verifySslVserver verifies that the vserver exists as virtual server of the configured type
+// NetScaler ADC binds certificates to load balancing, content switching, gateway, authentication and GSLB virtual servers
+// through the same ssl vserver binding, the type only determines which virtual server resource is verified.
+// The SSL settings of every SSL virtual server are managed by the ssl vserver entity, which is named after the virtual server,
+// see "bind ssl vserver <vServerName> -certkeyName <string>" in the SSL section of the NetScaler ADC command reference
+// and the sslvserver_sslcertkey_binding resource in the SSL section of the NetScaler ADC NITRO API reference.
+// Gateway virtual servers, for example, are configured with "bind ssl vserver <vpn vserver name> -certkeyName <certkey>".
+func (l Launcher) verifySslVserver(c *nitro.Client, t config.Target, name string, vserverType string) error {
+	var (
+		err   error
+		found bool
+	)
+
+	switch vserverType {
+	case "":
+		return nil
+	case config.SSL_VSERVER_TYPE_LB:
+		found, err = vserverExists[nitroConfig.LbVserver](c, name)
+	case config.SSL_VSERVER_TYPE_CS:
+		found, err = vserverExists[nitroConfig.CsVserver](c, name)
+	case config.SSL_VSERVER_TYPE_VPN:
+		found, err = vserverExists[nitroConfig.VpnVserver](c, name)
+	case config.SSL_VSERVER_TYPE_AAA:
+		found, err = vserverExists[nitroConfig.AuthenticationVserver](c, name)
+	case config.SSL_VSERVER_TYPE_GSLB:
+		found, err = vserverExists[nitroConfig.GslbVserver](c, name)
+	default:
+		return fmt.Errorf("invalid type %s for vserver %s", vserverType, name)
+	}
+
+	if err != nil {
+		slog.Debug("could not verify vserver", "target", t, "vserver", name, "type", vserverType, "error", err)
+		return fmt.Errorf("could not find %s vserver %s in organization %s environment %s with message %w", vserverType, name, t.Organization, t.Environment, err)
+	}
+	if !found {
+		return fmt.Errorf("could not find %s vserver %s in organization %s environment %s", vserverType, name, t.Organization, t.Environment)
+	}
+	return nil
+}
+
+// vserverExists reports whether a virtual server of type T exists with the given name
+func vserverExists[T any](c *nitro.Client, name string) (bool, error) {
+	res, err := nitro.ExecuteNitroRequest[T](c, &nitro.Request[T]{
+		ResourceName: name,
+		Attributes:   []string{"name"},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(res.Data) > 0, nil
+}
+
+// bindSslService binds the certificate to the ssl services of the installation to which it is not bound yet
+// Binding continues when an ssl service fails, every failed binding is returned as BindingError
+func (l Launcher) bindSslService(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		errs     []error
+		bindings *nitro.Response[nitroConfig.SslCertKeyServiceBinding]
+		bound    = make(map[string]bool)
+	)
+	slog.Info("bind certificate to ssl services", "target", i.Target)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	if bindings, err = controller.GetServiceBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl service bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get service bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+	for _, boundTo := range bindings.Data {
+		bound[boundTo.ServiceName] = true
+	}
+	slog.Debug("found existing bindings for certificate", "target", i.Target, "certificate", name, "count", len(bound))
+
+	for _, bindTo := range i.SslServices {
+		if bound[bindTo.Name] {
+			slog.Debug("certificate already bound to ssl service", "target", i.Target, "certificate", name, "service", bindTo.Name)
+			continue
+		}
+
+		slog.Debug("bind certificate to ssl service", "target", i.Target, "certificate", name, "service", bindTo.Name)
+		if err = l.bindServerCertificate(c, BINDING_ENDPOINT_SERVICE, bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
+			slog.Error("could not bind certificate to ssl service", "target", i.Target, "certificate", name, "service", bindTo.Name, "error", err)
+			errs = append(errs, BindingError{Target: i.Target, CertKeyName: certKeyName, Endpoint: BINDING_ENDPOINT_SERVICE, Name: bindTo.Name, Err: err})
+			continue
+		}
+		bound[bindTo.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// getNitroClient returns a client for the primary node of the target environment, switched to the partition of the target
+func (l Launcher) getNitroClient(t config.Target) (*nitro.Client, error) {
+	var (
+		err         error
+		environment registry.Environment
+		client      *nitro.Client
+	)
+
+	if environment, err = l.getEnvironment(t); err != nil {
+		return nil, err
+	}
+	if client, err = environment.GetPrimaryNitroClient(); err != nil {
+		return nil, fmt.Errorf("could not connect to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	if err = netscaleradc.SwitchPartition(client, t.Partition); err != nil {
+		return nil, fmt.Errorf("could not connect to organization %s environment %s with message %w", t.Organization, t.Environment, err)
+	}
+	return client, nil
+}
+
+// pruneSslVserverBindings unbinds the certificate from the ssl vservers which are not defined in the installation
+func (l Launcher) pruneSslVserverBindings(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		bindings *nitro.Response[nitroConfig.SslCertKeySslVserverBinding]
+		desired  = make(map[string]bool)
+	)
+	slog.Info("prune ssl vserver bindings", "target", i.Target, "certificate", name)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	for _, bindTo := range i.SslVirtualServers {
+		desired[bindTo.Name] = true
+	}
+
+	if bindings, err = controller.GetSslVserverBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl vserver bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get vserver bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+
+	for _, boundTo := range bindings.Data {
+		if desired[boundTo.ServerName] {
+			continue
+		}
+		slog.Debug("unbind certificate from ssl vserver", "target", i.Target, "certificate", name, "vserver", boundTo.ServerName)
+		if _, err = controller.UnbindSslVserver(boundTo.ServerName, certKeyName); err != nil {
+			return fmt.Errorf("could not unbind certificate %s from vserver %s in organization %s environment %s with message %w", certKeyName, boundTo.ServerName, i.Target.Organization, i.Target.Environment, err)
+		}
+	}
+	return nil
+}
+
+// pruneSslServiceBindings unbinds the certificate from the ssl services which are not defined in the installation
+func (l Launcher) pruneSslServiceBindings(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		bindings *nitro.Response[nitroConfig.SslCertKeyServiceBinding]
+		desired  = make(map[string]bool)
+	)
+	slog.Info("prune ssl service bindings", "target", i.Target, "certificate", name)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	for _, bindTo := range i.SslServices {
+		desired[bindTo.Name] = true
+	}
+
+	if bindings, err = controller.GetServiceBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl service bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get service bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+
+	for _, boundTo := range bindings.Data {
+		if desired[boundTo.ServiceName] {
+			continue
+		}
+		slog.Debug("unbind certificate from ssl service", "target", i.Target, "certificate", name, "service", boundTo.ServiceName)
+		if _, err = controller.UnbindSslService(boundTo.ServiceName, certKeyName); err != nil {
+			return fmt.Errorf("could not unbind certificate %s from service %s in organization %s environment %s with message %w", certKeyName, boundTo.ServiceName, i.Target.Organization, i.Target.Environment, err)
+		}
+	}
+	return nil
+}
+
+func (l Launcher) bindSslServiceGroups(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		errs     []error
+		bindings *nitro.Response[nitroConfig.SslCertKeyServiceGroupBinding]
+		bound    = make(map[string]bool)
+	)
+	slog.Info("bind certificate to ssl service groups", "target", i.Target)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	if bindings, err = controller.GetServiceGroupBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl service group bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get service group bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+	for _, boundTo := range bindings.Data {
+		bound[boundTo.ServiceGroupName] = true
+	}
+
+	for _, bindTo := range i.SslServiceGroups {
+		if bound[bindTo.Name] {
+			slog.Debug("certificate already bound to ssl service group", "target", i.Target, "certificate", name, "servicegroup", bindTo.Name)
+			continue
+		}
+		slog.Debug("bind certificate to ssl service group", "target", i.Target, "certificate", name, "servicegroup", bindTo.Name)
+		if err = l.bindServerCertificate(c, BINDING_ENDPOINT_SERVICEGROUP, bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
+			slog.Error("could not bind certificate to ssl service group", "target", i.Target, "certificate", name, "servicegroup", bindTo.Name, "error", err)
+			errs = append(errs, BindingError{Target: i.Target, CertKeyName: certKeyName, Endpoint: BINDING_ENDPOINT_SERVICEGROUP, Name: bindTo.Name, Err: err})
+			continue
+		}
+		bound[bindTo.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+func (l Launcher) bindSslProfiles(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		errs     []error
+		bindings *nitro.Response[nitroConfig.SslCertKeySslProfileBinding]
+		bound    = make(map[string]bool)
+	)
+	slog.Info("bind certificate to ssl profiles", "target", i.Target)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	if bindings, err = controller.GetSslProfileBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl profile bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get ssl profile bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+	for _, boundTo := range bindings.Data {
+		bound[boundTo.SslProfileName] = true
+	}
+
+	for _, bindTo := range i.SslProfiles {
+		if bound[bindTo.Name] {
+			slog.Debug("certificate already bound to ssl profile", "target", i.Target, "certificate", name, "profile", bindTo.Name)
+			continue
+		}
+		slog.Debug("bind certificate to ssl profile", "target", i.Target, "certificate", name, "profile", bindTo.Name)
+		if _, err = controller.BindSslProfile(bindTo.Name, certKeyName, bindTo.SniEnabled); err != nil {
+			slog.Error("could not bind certificate to ssl profile", "target", i.Target, "certificate", name, "profile", bindTo.Name, "error", err)
+			errs = append(errs, BindingError{Target: i.Target, CertKeyName: certKeyName, Endpoint: BINDING_ENDPOINT_PROFILE, Name: bindTo.Name, Err: err})
+			continue
+		}
+		bound[bindTo.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// bindCaCertificates binds the CA certificates of the installation to its ssl vservers, ssl services and ssl service groups
+// Existing CA bindings are left untouched, so changed OCSP and CRL check options are not applied to them
+func (l Launcher) bindCaCertificates(c *nitro.Client, name string, i config.Installation, issuerCertKeyName string) error {
+	var (
+		err  error
+		errs []error
+	)
+	slog.Info("bind ca certificates", "target", i.Target, "certificate", name)
+
+	for _, ca := range i.CaCertificates {
+		caCertKeyName := ca.CertKeyName
+		if ca.Issuer {
+			caCertKeyName = issuerCertKeyName
+		}
+
+		if ca.File != "" {
+			if err = l.configureCaCertKey(c, i.Target, caCertKeyName, ca.File); err != nil {
+				slog.Error("could not configure ca certificate", "target", i.Target, "certkey", caCertKeyName, "error", err)
+				errs = append(errs, l.getCaCertificateBindingErrors(i, caCertKeyName, err)...)
+				continue
+			}
+		}
+
+		if err = l.bindCaCertificate(c, i, caCertKeyName, ca.OcspCheck, ca.CrlCheck); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// getCaCertificateBindingErrors returns a BindingError for every endpoint of the installation,
+// used when the CA certkey cannot be configured and the CA certificate cannot be bound to any endpoint
+func (l Launcher) getCaCertificateBindingErrors(i config.Installation, caCertKeyName string, err error) []error {
+	var output []error
+
+	for _, e := range l.getInstallationEndpoints(i) {
+		output = append(output, BindingError{Target: i.Target, CertKeyName: caCertKeyName, Endpoint: e.Endpoint, Name: e.Name, Err: err})
+	}
+	return output
+}
+
+// configureCaCertKey creates the certkey for a CA certificate file on the target, unless the certkey already exists
+func (l Launcher) configureCaCertKey(c *nitro.Client, t config.Target, certKeyName string, file string) error {
+	var (
+		err       error
+		unwrapErr error
+	)
+	controller := controllers.NewSslCertKeyController(c)
+
+	if _, err = controller.Get(certKeyName, nil); err == nil {
+		slog.Debug("found existing ca certkey on target", "target", t, "certkey", certKeyName)
+		return nil
+	}
+
+	unwrapErr = errors.Unwrap(err)
+	if !errors.Is(unwrapErr, nitro.NSERR_SSL_NOCERT) {
+		slog.Debug("could not verify if ca certificate exists on target", "target", t, "certkey", certKeyName, "error", err)
+		return fmt.Errorf("could not verify if ca certificate %s exists in organization %s environment %s with message %w", certKeyName, t.Organization, t.Environment, err)
+	}
+
+	slog.Debug("creating ca certkey on target", "target", t, "certkey", certKeyName, "file", file)
+	if _, err = controller.Add(certKeyName, file, ""); err != nil {
+		return fmt.Errorf("could not add ca certificate %s to organization %s environment %s with message %w", certKeyName, t.Organization, t.Environment, err)
+	}
+	return nil
+}
+
+// bindCaCertificate binds the CA certkey to every endpoint of the installation, continuing with the remaining endpoints when binding fails
+func (l Launcher) bindCaCertificate(c *nitro.Client, i config.Installation, caCertKeyName string, ocspCheck string, crlCheck string) error {
+	var (
+		err  error
+		errs []error
+	)
+
+	for _, e := range l.getInstallationEndpoints(i) {
+		if err = l.bindCaCertificateToEndpoint(c, e.Endpoint, e.Name, caCertKeyName, ocspCheck, crlCheck); err != nil {
+			slog.Error("could not bind ca certificate", "target", i.Target, "certkey", caCertKeyName, "endpoint", e.Endpoint, "name", e.Name, "error", err)
+			errs = append(errs, BindingError{Target: i.Target, CertKeyName: caCertKeyName, Endpoint: e.Endpoint, Name: e.Name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pruneSslServiceGroupBindings unbinds the certificate from the ssl service groups which are not defined in the installation
+func (l Launcher) pruneSslServiceGroupBindings(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		bindings *nitro.Response[nitroConfig.SslCertKeyServiceGroupBinding]
+		desired  = make(map[string]bool)
+	)
+	slog.Info("prune ssl service group bindings", "target", i.Target, "certificate", name)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	for _, bindTo := range i.SslServiceGroups {
+		desired[bindTo.Name] = true
+	}
+
+	if bindings, err = controller.GetServiceGroupBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl service group bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get service group bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+
+	for _, boundTo := range bindings.Data {
+		if desired[boundTo.ServiceGroupName] {
+			continue
+		}
+		slog.Debug("unbind certificate from ssl service group", "target", i.Target, "certificate", name, "servicegroup", boundTo.ServiceGroupName)
+		if _, err = controller.UnbindSslServiceGroup(boundTo.ServiceGroupName, certKeyName); err != nil {
+			return fmt.Errorf("could not unbind certificate %s from service group %s in organization %s environment %s with message %w", certKeyName, boundTo.ServiceGroupName, i.Target.Organization, i.Target.Environment, err)
+		}
+	}
+	return nil
+}
+
+// pruneSslProfileBindings unbinds the certificate from the ssl profiles which are not defined in the installation
+func (l Launcher) pruneSslProfileBindings(c *nitro.Client, name string, i config.Installation) error {
+	var (
+		err      error
+		bindings *nitro.Response[nitroConfig.SslCertKeySslProfileBinding]
+		desired  = make(map[string]bool)
+	)
+	slog.Info("prune ssl profile bindings", "target", i.Target, "certificate", name)
+	certKeyName := l.getSslCertKeyName(i, name)
+	controller := controllers.NewSslCertKeyController(c)
+
+	for _, bindTo := range i.SslProfiles {
+		desired[bindTo.Name] = true
+	}
+
+	if bindings, err = controller.GetSslProfileBinding(certKeyName, nil); err != nil {
+		slog.Debug("could not get ssl profile bindings", "target", i.Target, "certificate", name, "error", err)
+		return fmt.Errorf("could not get ssl profile bindings for certificate %s in organization %s environment %s with message %w", certKeyName, i.Target.Organization, i.Target.Environment, err)
+	}
+
+	for _, boundTo := range bindings.Data {
+		if desired[boundTo.SslProfileName] {
+			continue
+		}
+		slog.Debug("unbind certificate from ssl profile", "target", i.Target, "certificate", name, "profile", boundTo.SslProfileName)
+		if _, err = controller.UnbindSslProfile(boundTo.SslProfileName, certKeyName); err != nil {
+			return fmt.Errorf("could not unbind certificate %s from ssl profile %s in organization %s environment %s with message %w", certKeyName, boundTo.SslProfileName, i.Target.Organization, i.Target.Environment, err)
+		}
+	}
+	return nil
+}
+
 func (l Launcher) getEnvironment(t config.Target) (registry.Environment, error) {
 	for _, org := range l.organizations {
 		if t.Organization == org.Name {
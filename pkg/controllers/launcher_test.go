@@ -0,0 +1,700 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro"
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+// fakeNitroCertKeyName is the name of the certkey bound by the tests, which are executed for certificate "test"
+const fakeNitroCertKeyName = "LENS_test"
+
+// fakeNitroBinding maps a NITRO binding resource to the endpoint type and the attribute holding the endpoint name
+type fakeNitroBinding struct {
+	endpoint  string
+	attribute string
+}
+
+// fakeNitroCertKeyBinding holds the options of a certkey bound to an endpoint
+type fakeNitroCertKeyBinding struct {
+	SniCert   bool   `json:"snicert"`
+	Ca        bool   `json:"ca"`
+	OcspCheck string `json:"ocspcheck"`
+	CrlCheck  string `json:"crlcheck"`
+}
+
+var (
+	fakeNitroListBindings = map[string]fakeNitroBinding{
+		"sslcertkey_sslvserver_binding":   {endpoint: BINDING_ENDPOINT_VSERVER, attribute: "servername"},
+		"sslcertkey_service_binding":      {endpoint: BINDING_ENDPOINT_SERVICE, attribute: "servicename"},
+		"sslcertkey_servicegroup_binding": {endpoint: BINDING_ENDPOINT_SERVICEGROUP, attribute: "servicegroupname"},
+		"sslcertkey_sslprofile_binding":   {endpoint: BINDING_ENDPOINT_PROFILE, attribute: "sslprofilename"},
+	}
+	fakeNitroBindBindings = map[string]fakeNitroBinding{
+		"sslvserver_sslcertkey_binding":      {endpoint: BINDING_ENDPOINT_VSERVER, attribute: "vservername"},
+		"sslservice_sslcertkey_binding":      {endpoint: BINDING_ENDPOINT_SERVICE, attribute: "servicename"},
+		"sslservicegroup_sslcertkey_binding": {endpoint: BINDING_ENDPOINT_SERVICEGROUP, attribute: "servicegroupname"},
+		"sslprofile_sslcertkey_binding":      {endpoint: BINDING_ENDPOINT_PROFILE, attribute: "sslprofilename"},
+	}
+)
+
+// fakeNitroCertKey holds the certificate and key files of a certkey
+type fakeNitroCertKey struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// fakeNitro fakes the NITRO endpoints to list, bind and unbind certkey bindings, from the certkey side as well as from the endpoint side,
+// to log in, to get and update certkeys, and counts the requests to save the running configuration
+type fakeNitro struct {
+	mutex    sync.Mutex
+	bindings map[string]map[string]map[string]fakeNitroCertKeyBinding
+	certKeys map[string]fakeNitroCertKey
+	failing  map[string]bool
+	saved    int
+}
+
+// newFakeNitro returns a fake NITRO server with fakeNitroCertKeyName bound to the existing endpoints
+// Binding fakeNitroCertKeyName to failing endpoints fails, other certkeys can still be bound to them
+func newFakeNitro(existing map[string][]string, failing []string) *fakeNitro {
+	f := &fakeNitro{
+		bindings: make(map[string]map[string]map[string]fakeNitroCertKeyBinding),
+		certKeys: make(map[string]fakeNitroCertKey),
+		failing:  make(map[string]bool),
+	}
+	for endpoint, names := range existing {
+		for _, name := range names {
+			f.bind(endpoint, name, fakeNitroCertKeyName, fakeNitroCertKeyBinding{})
+		}
+	}
+	for _, name := range failing {
+		f.failing[name] = true
+	}
+	return f
+}
+
+func (f *fakeNitro) bind(endpoint string, name string, certKey string, binding fakeNitroCertKeyBinding) {
+	if f.bindings[endpoint] == nil {
+		f.bindings[endpoint] = make(map[string]map[string]fakeNitroCertKeyBinding)
+	}
+	if f.bindings[endpoint][name] == nil {
+		f.bindings[endpoint][name] = make(map[string]fakeNitroCertKeyBinding)
+	}
+	f.bindings[endpoint][name][certKey] = binding
+}
+
+// getBound returns the names of the endpoints fakeNitroCertKeyName is bound to, sorted by name
+func (f *fakeNitro) getBound(endpoint string) []string {
+	var output []string
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for name, certKeys := range f.bindings[endpoint] {
+		if _, found := certKeys[fakeNitroCertKeyName]; found {
+			output = append(output, name)
+		}
+	}
+	sort.Strings(output)
+	return output
+}
+
+// getCertKey returns the certificate and key files of the certkey
+func (f *fakeNitro) getCertKey(name string) fakeNitroCertKey {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.certKeys[name]
+}
+
+// getSaved returns the number of times the running configuration was saved
+func (f *fakeNitro) getSaved() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.saved
+}
+
+// getCertKeys returns the certkeys bound to the endpoint
+func (f *fakeNitro) getCertKeys(endpoint string, name string) map[string]fakeNitroCertKeyBinding {
+	output := make(map[string]fakeNitroCertKeyBinding)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for certKey, binding := range f.bindings[endpoint][name] {
+		output[certKey] = binding
+	}
+	return output
+}
+
+func (f *fakeNitro) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	resource, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/nitro/v1/config/"), "/")
+	if resource == "nsconfig" && r.Method == http.MethodPost && r.URL.Query().Get("action") == "save" {
+		f.saved++
+		f.writeResponse(w, map[string]interface{}{})
+		return
+	}
+	if resource == "login" && r.Method == http.MethodPost {
+		f.writeResponse(w, map[string]interface{}{})
+		return
+	}
+	if resource == "sslcertkey" {
+		f.serveCertKey(w, r, name)
+		return
+	}
+	if binding, found := fakeNitroListBindings[resource]; found && r.Method == http.MethodGet {
+		data := []map[string]interface{}{}
+		for boundTo, certKeys := range f.bindings[binding.endpoint] {
+			if _, bound := certKeys[name]; bound {
+				data = append(data, map[string]interface{}{"certkey": name, binding.attribute: boundTo})
+			}
+		}
+		f.writeResponse(w, map[string]interface{}{resource: data})
+		return
+	}
+
+	binding, found := fakeNitroBindBindings[resource]
+	if !found {
+		f.writeError(w, http.StatusNotFound, "unknown resource "+resource)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		data := []map[string]interface{}{}
+		for certKey, options := range f.bindings[binding.endpoint][name] {
+			data = append(data, map[string]interface{}{binding.attribute: name, "certkeyname": certKey, "snicert": options.SniCert, "ca": options.Ca, "ocspcheck": options.OcspCheck, "crlcheck": options.CrlCheck})
+		}
+		f.writeResponse(w, map[string]interface{}{resource: data})
+	case http.MethodPut:
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var (
+			attributes map[string]interface{}
+			options    fakeNitroCertKeyBinding
+		)
+		if err := json.Unmarshal(body[resource], &attributes); err != nil {
+			f.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := json.Unmarshal(body[resource], &options); err != nil {
+			f.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		boundTo, _ := attributes[binding.attribute].(string)
+		certKey, _ := attributes["certkeyname"].(string)
+		if f.failing[boundTo] && certKey == fakeNitroCertKeyName {
+			f.writeError(w, http.StatusConflict, "Resource already exists")
+			return
+		}
+		f.bind(binding.endpoint, boundTo, certKey, options)
+		f.writeResponse(w, nil)
+	case http.MethodDelete:
+		var certKey string
+		for _, arg := range strings.Split(r.URL.Query().Get("args"), ",") {
+			if key, value, _ := strings.Cut(arg, ":"); key == "certkeyname" {
+				certKey = value
+			}
+		}
+		delete(f.bindings[binding.endpoint][name], certKey)
+		f.writeResponse(w, nil)
+	default:
+		f.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// serveCertKey gets or updates the certificate and key files of a certkey
+func (f *fakeNitro) serveCertKey(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		data := []map[string]interface{}{}
+		if certKey, found := f.certKeys[name]; found {
+			data = append(data, map[string]interface{}{"certkey": name, "cert": certKey.Cert, "key": certKey.Key})
+		}
+		f.writeResponse(w, map[string]interface{}{"sslcertkey": data})
+	case http.MethodPut:
+		var body struct {
+			SslCertKey struct {
+				CertKey string `json:"certkey"`
+				fakeNitroCertKey
+			} `json:"sslcertkey"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		f.certKeys[body.SslCertKey.CertKey] = body.SslCertKey.fakeNitroCertKey
+		f.writeResponse(w, map[string]interface{}{})
+	default:
+		f.writeError(w, http.StatusMethodNotAllowed, "unsupported method "+r.Method)
+	}
+}
+
+func (f *fakeNitro) writeResponse(w http.ResponseWriter, data map[string]interface{}) {
+	output := map[string]interface{}{"errorcode": 0, "message": "Done", "severity": "NONE"}
+	for k, v := range data {
+		output[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(output)
+}
+
+func (f *fakeNitro) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errorcode": 273, "message": message, "severity": "ERROR"})
+}
+
+// newTestNitroClient returns a nitro client connecting to the fake NITRO server over HTTP
+func newTestNitroClient(t *testing.T, server *httptest.Server) *nitro.Client {
+	t.Helper()
+
+	client, err := nitro.NewClient("test", strings.TrimPrefix(server.URL, "http://"), nitro.Credentials{Username: "nsroot", Password: "nsroot"}, nitro.ConnectionSettings{UseSsl: false, Timeout: 5000})
+	if err != nil {
+		t.Fatalf("could not create nitro client: %v", err)
+	}
+	return client
+}
+
+// getFailedBindings returns the endpoint names of the binding errors in err, sorted by name
+func getFailedBindings(err error) []string {
+	var output []string
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			output = append(output, getFailedBindings(e)...)
+		}
+	}
+	if bindingErr, ok := err.(BindingError); ok {
+		output = append(output, bindingErr.Name)
+	}
+	sort.Strings(output)
+	return output
+}
+
+func TestLauncher_bindCertificate(t *testing.T) {
+	tests := []struct {
+		name       string
+		vservers   []string
+		services   []string
+		existing   map[string][]string
+		failing    []string
+		prune      bool
+		wantFailed []string
+		wantBound  map[string][]string
+	}{
+		{
+			name:       "partial failure",
+			vservers:   []string{"VS_A", "VS_B"},
+			services:   []string{"SVC_A"},
+			failing:    []string{"VS_B"},
+			wantFailed: []string{"VS_B"},
+			wantBound: map[string][]string{
+				BINDING_ENDPOINT_VSERVER: {"VS_A"},
+				BINDING_ENDPOINT_SERVICE: {"SVC_A"},
+			},
+		},
+		{
+			name:     "full success with pruning",
+			vservers: []string{"VS_A", "VS_B"},
+			services: []string{"SVC_A"},
+			existing: map[string][]string{
+				BINDING_ENDPOINT_VSERVER: {"VS_A", "VS_OLD"},
+				BINDING_ENDPOINT_SERVICE: {"SVC_OLD"},
+			},
+			prune: true,
+			wantBound: map[string][]string{
+				BINDING_ENDPOINT_VSERVER: {"VS_A", "VS_B"},
+				BINDING_ENDPOINT_SERVICE: {"SVC_A"},
+			},
+		},
+		{
+			name:     "no pruning when any binding fails",
+			vservers: []string{"VS_A", "VS_B"},
+			services: []string{"SVC_A"},
+			existing: map[string][]string{
+				BINDING_ENDPOINT_VSERVER: {"VS_OLD"},
+				BINDING_ENDPOINT_SERVICE: {"SVC_OLD"},
+			},
+			failing:    []string{"VS_B", "SVC_A"},
+			prune:      true,
+			wantFailed: []string{"SVC_A", "VS_B"},
+			wantBound: map[string][]string{
+				BINDING_ENDPOINT_VSERVER: {"VS_A", "VS_OLD"},
+				BINDING_ENDPOINT_SERVICE: {"SVC_OLD"},
+			},
+		},
+		{
+			name:     "no pruning of endpoint types without endpoints",
+			vservers: []string{"VS_A"},
+			existing: map[string][]string{
+				BINDING_ENDPOINT_VSERVER:      {"VS_OLD"},
+				BINDING_ENDPOINT_SERVICE:      {"SVC_OLD"},
+				BINDING_ENDPOINT_SERVICEGROUP: {"SG_OLD"},
+				BINDING_ENDPOINT_PROFILE:      {"PROFILE_OLD"},
+			},
+			prune: true,
+			wantBound: map[string][]string{
+				BINDING_ENDPOINT_VSERVER:      {"VS_A"},
+				BINDING_ENDPOINT_SERVICE:      {"SVC_OLD"},
+				BINDING_ENDPOINT_SERVICEGROUP: {"SG_OLD"},
+				BINDING_ENDPOINT_PROFILE:      {"PROFILE_OLD"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeNitro(tt.existing, tt.failing)
+			server := httptest.NewServer(f)
+			defer server.Close()
+
+			client := newTestNitroClient(t, server)
+
+			i := config.Installation{
+				Target:        config.Target{Organization: "corelayer", Environment: "test"},
+				PruneBindings: tt.prune,
+			}
+			for _, name := range tt.vservers {
+				i.AddSslVirtualServer(name, false)
+			}
+			for _, name := range tt.services {
+				i.AddSslService(name, false)
+			}
+
+			err := Launcher{}.bindCertificate(client, "test", i, "")
+			if len(tt.wantFailed) == 0 {
+				if err != nil {
+					t.Fatalf("bindCertificate() error = %v, want nil", err)
+				}
+			} else {
+				if !onlyBindingErrors(err) {
+					t.Errorf("bindCertificate() error = %v, want binding errors only", err)
+				}
+				if got := getFailedBindings(err); !reflect.DeepEqual(got, tt.wantFailed) {
+					t.Errorf("bindCertificate() failed bindings = %v, want %v", got, tt.wantFailed)
+				}
+			}
+
+			for endpoint, want := range tt.wantBound {
+				if got := f.getBound(endpoint); !reflect.DeepEqual(got, want) {
+					t.Errorf("bound %s = %v, want %v", endpoint, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLauncher_bindServerCertificate(t *testing.T) {
+	tests := []struct {
+		name       string
+		sniEnabled bool
+		failing    []string
+		wantErr    bool
+		want       []string
+	}{
+		{name: "replace server certificate", sniEnabled: false, want: []string{"CA_CERT", fakeNitroCertKeyName, "SNI_CERT"}},
+		{name: "keep server certificate for sni", sniEnabled: true, want: []string{"CA_CERT", fakeNitroCertKeyName, "OLD_CERT", "SNI_CERT"}},
+		{name: "restore server certificate on failure", sniEnabled: false, failing: []string{"VS_A"}, wantErr: true, want: []string{"CA_CERT", "OLD_CERT", "SNI_CERT"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeNitro(nil, tt.failing)
+			f.bind(BINDING_ENDPOINT_VSERVER, "VS_A", "OLD_CERT", fakeNitroCertKeyBinding{})
+			f.bind(BINDING_ENDPOINT_VSERVER, "VS_A", "SNI_CERT", fakeNitroCertKeyBinding{SniCert: true})
+			f.bind(BINDING_ENDPOINT_VSERVER, "VS_A", "CA_CERT", fakeNitroCertKeyBinding{Ca: true})
+			server := httptest.NewServer(f)
+			defer server.Close()
+
+			err := Launcher{}.bindServerCertificate(newTestNitroClient(t, server), BINDING_ENDPOINT_VSERVER, "VS_A", fakeNitroCertKeyName, tt.sniEnabled)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("bindServerCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got []string
+			for certKey := range f.getCertKeys(BINDING_ENDPOINT_VSERVER, "VS_A") {
+				got = append(got, certKey)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bound certkeys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLauncher_bindCaCertificate(t *testing.T) {
+	f := newFakeNitro(nil, nil)
+	f.bind(BINDING_ENDPOINT_VSERVER, "VS_A", "CA_CERT", fakeNitroCertKeyBinding{Ca: true, OcspCheck: config.CA_CERTIFICATE_CHECK_OPTIONAL})
+	f.bind(BINDING_ENDPOINT_SERVICE, "SVC_A", "CA_CERT", fakeNitroCertKeyBinding{Ca: true, OcspCheck: config.CA_CERTIFICATE_CHECK_MANDATORY})
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	i := config.Installation{
+		Target: config.Target{Organization: "corelayer", Environment: "test"},
+	}
+	i.AddSslVirtualServer("VS_A", false)
+	i.AddSslService("SVC_A", false)
+	i.AddSslProfile("PROFILE_A", false)
+
+	if err := (Launcher{}).bindCaCertificate(newTestNitroClient(t, server), i, "CA_CERT", config.CA_CERTIFICATE_CHECK_MANDATORY, ""); err != nil {
+		t.Fatalf("bindCaCertificate() error = %v, want nil", err)
+	}
+
+	want := map[string]fakeNitroCertKeyBinding{"CA_CERT": {Ca: true, OcspCheck: config.CA_CERTIFICATE_CHECK_MANDATORY}}
+	for _, e := range []installationEndpoint{{BINDING_ENDPOINT_VSERVER, "VS_A"}, {BINDING_ENDPOINT_SERVICE, "SVC_A"}, {BINDING_ENDPOINT_PROFILE, "PROFILE_A"}} {
+		if got := f.getCertKeys(e.Endpoint, e.Name); !reflect.DeepEqual(got, want) {
+			t.Errorf("bound certkeys of %s %s = %v, want %v", e.Endpoint, e.Name, got, want)
+		}
+	}
+}
+
+func TestLauncher_importBindings(t *testing.T) {
+	f := newFakeNitro(map[string][]string{
+		BINDING_ENDPOINT_SERVICE:      {"SVC_A"},
+		BINDING_ENDPOINT_SERVICEGROUP: {"SG_A"},
+		BINDING_ENDPOINT_PROFILE:      {"PROFILE_A"},
+	}, nil)
+	f.bind(BINDING_ENDPOINT_VSERVER, "VS_A", fakeNitroCertKeyName, fakeNitroCertKeyBinding{SniCert: true})
+	f.bind(BINDING_ENDPOINT_VSERVER, "VS_A", "CA_CERT", fakeNitroCertKeyBinding{Ca: true, OcspCheck: config.CA_CERTIFICATE_CHECK_MANDATORY})
+	f.bind(BINDING_ENDPOINT_SERVICE, "SVC_A", "CA_CERT", fakeNitroCertKeyBinding{Ca: true, OcspCheck: config.CA_CERTIFICATE_CHECK_MANDATORY})
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	target := config.Target{Organization: "corelayer", Environment: "test"}
+	got := config.Installation{Target: target}
+	if err := (Launcher{}).importBindings(newTestNitroClient(t, server), target, fakeNitroCertKeyName, &got); err != nil {
+		t.Fatalf("importBindings() error = %v, want nil", err)
+	}
+
+	want := config.Installation{Target: target}
+	want.AddSslVirtualServer("VS_A", true)
+	want.AddSslService("SVC_A", false)
+	want.AddSslServiceGroup("SG_A", false)
+	want.AddSslProfile("PROFILE_A", false)
+	want.AddCaCertificate("CA_CERT", config.CA_CERTIFICATE_CHECK_MANDATORY, "")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("importBindings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLauncher_unbindCertificate(t *testing.T) {
+	f := newFakeNitro(map[string][]string{
+		BINDING_ENDPOINT_VSERVER:      {"VS_A", "VS_OTHER"},
+		BINDING_ENDPOINT_SERVICE:      {"SVC_OTHER"},
+		BINDING_ENDPOINT_SERVICEGROUP: {"SG_OTHER"},
+		BINDING_ENDPOINT_PROFILE:      {"PROFILE_OTHER"},
+	}, nil)
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	i := config.Installation{
+		Target: config.Target{Organization: "corelayer", Environment: "test"},
+	}
+	i.AddSslVirtualServer("VS_A", false)
+
+	if err := (Launcher{}).unbindCertificate(newTestNitroClient(t, server), "test", i); err != nil {
+		t.Fatalf("unbindCertificate() error = %v, want nil", err)
+	}
+
+	for _, endpoint := range []string{BINDING_ENDPOINT_VSERVER, BINDING_ENDPOINT_SERVICE, BINDING_ENDPOINT_SERVICEGROUP, BINDING_ENDPOINT_PROFILE} {
+		if got := f.getBound(endpoint); len(got) != 0 {
+			t.Errorf("bound %s = %v, want none", endpoint, got)
+		}
+	}
+}
+
+func TestLauncher_processCertificates(t *testing.T) {
+	certs := map[string]config.Certificate{
+		"first": {
+			Name:    "first",
+			Request: config.Request{User: "unknown", KeyType: config.ACME_KEY_TYPE_EC256, Challenge: config.Challenge{Provider: "first"}},
+		},
+		"second": {
+			Name:    "second",
+			Request: config.Request{User: "unknown", KeyType: config.ACME_KEY_TYPE_EC256, Challenge: config.Challenge{Provider: "second"}},
+		},
+	}
+
+	err := NewLauncher(Loader{}, nil, nil, nil).processCertificates(certs, false)
+	if err == nil {
+		t.Fatal("processCertificates() error = nil, want error")
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("processCertificates() error = %v, want an error for every certificate", err)
+	}
+}
+
+func TestLauncher_hasPreferredChain(t *testing.T) {
+	chain := []*x509.Certificate{
+		{Issuer: pkix.Name{CommonName: "ISRG Root X2"}},
+		{Issuer: pkix.Name{CommonName: "ISRG Root X1"}},
+	}
+
+	tests := []struct {
+		name           string
+		preferredChain string
+		want           bool
+	}{
+		{name: "intermediate issuer", preferredChain: "ISRG Root X2", want: true},
+		{name: "top issuer", preferredChain: "ISRG Root X1", want: true},
+		{name: "not in chain", preferredChain: "DST Root CA X3", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Launcher{}).hasPreferredChain(chain, tt.preferredChain); got != tt.want {
+				t.Errorf("hasPreferredChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLauncher_isLifetimeRenewalDue(t *testing.T) {
+	notBefore := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaf := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * 24 * time.Hour)}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{name: "recently issued", now: notBefore.Add(24 * time.Hour), want: false},
+		{name: "one third remaining", now: notBefore.Add(60 * 24 * time.Hour), want: true},
+		{name: "expired", now: notBefore.Add(91 * 24 * time.Hour), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Launcher{}).isLifetimeRenewalDue("test", leaf, tt.now); got != tt.want {
+				t.Errorf("isLifetimeRenewalDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestOrganization returns an organization with environment "test", which has the address of the server as management address
+// Environments are decoded the same way as the configuration files of the application
+func newTestOrganization(t *testing.T, server *httptest.Server, useSsl bool) registry.Organization {
+	t.Helper()
+
+	var output registry.Organization
+	err := mapstructure.Decode(map[string]interface{}{
+		"name": "corelayer",
+		"environments": []map[string]interface{}{
+			{
+				"name": "test",
+				"type": "standalone",
+				"management": map[string]interface{}{
+					"name":    "test",
+					"address": server.Listener.Addr().String(),
+				},
+				"credentials": map[string]interface{}{
+					"username": "nsroot",
+					"password": "nsroot",
+				},
+				"connectionSettings": map[string]interface{}{
+					"useSsl":                    useSsl,
+					"timeout":                   5000,
+					"validateServerCertificate": false,
+				},
+			},
+		},
+	}, &output)
+	if err != nil {
+		t.Fatalf("could not decode organization: %v", err)
+	}
+	return output
+}
+
+func TestLauncher_safeReplaceDefaultCertificate(t *testing.T) {
+	previous := fakeNitroCertKey{Cert: "ns-server.cert", Key: "ns-server.key"}
+
+	tests := []struct {
+		name           string
+		httpsAvailable bool
+		useSsl         bool
+		revertOverHttp bool
+		wantErr        bool
+		wantReverted   bool
+	}{
+		{name: "verified over https", httpsAvailable: true, useSsl: false, revertOverHttp: false, wantErr: false, wantReverted: false},
+		{name: "reverted with environment settings", httpsAvailable: false, useSsl: false, revertOverHttp: false, wantErr: true, wantReverted: true},
+		{name: "no revert over http without revertOverHttp", httpsAvailable: false, useSsl: true, revertOverHttp: false, wantErr: true, wantReverted: false},
+		{name: "reverted over http", httpsAvailable: false, useSsl: true, revertOverHttp: true, wantErr: true, wantReverted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeNitro(nil, nil)
+			f.certKeys[LENS_DEFAULT_CERTKEY_NAME] = previous
+
+			// The management address only accepts HTTPS when it is available, otherwise NITRO is only served over plain HTTP
+			server := httptest.NewUnstartedServer(f)
+			if tt.httpsAvailable {
+				server.StartTLS()
+			} else {
+				server.Start()
+			}
+			defer server.Close()
+			client := httptest.NewServer(f)
+			defer client.Close()
+
+			i := config.Installation{
+				Target:                    config.Target{Organization: "corelayer", Environment: "test"},
+				ReplaceDefaultCertificate: true,
+				SafeReplace:               true,
+				RevertOverHttp:            tt.revertOverHttp,
+			}
+			r := config.Certificate{Name: "test"}
+			l := NewLauncher(Loader{}, []registry.Organization{newTestOrganization(t, server, tt.useSsl)}, nil, nil)
+
+			err := l.safeReplaceDefaultCertificate(newTestNitroClient(t, client), i, r, "test.key")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("safeReplaceDefaultCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			want := fakeNitroCertKey{Cert: l.getCertificatePath(i, r.Name), Key: "test.key"}
+			if tt.wantReverted {
+				want = previous
+			}
+			if got := f.getCertKey(LENS_DEFAULT_CERTKEY_NAME); got != want {
+				t.Errorf("safeReplaceDefaultCertificate() default certificate = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -17,10 +17,12 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 
@@ -28,14 +30,18 @@ import (
 )
 
 type Loader struct {
-	basePath   string
-	extensions []string
+	basePath         string
+	extensions       []string
+	nameFromFilename bool
 }
 
-func NewLoader(path string) Loader {
+// NewLoader returns a Loader for the certificate configuration files in path
+// When nameFromFilename is set, certificate configurations without a name are named after their filename without extension
+func NewLoader(path string, nameFromFilename bool) Loader {
 	return Loader{
-		basePath:   path,
-		extensions: []string{".yaml", ".yml"},
+		basePath:         path,
+		extensions:       []string{".yaml", ".yml", ".json", ".toml"},
+		nameFromFilename: nameFromFilename,
 	}
 
 }
@@ -143,6 +149,7 @@ func (l Loader) loadViper(path string) (*viper.Viper, error) {
 func (l Loader) loadVipers() (map[string]*viper.Viper, error) {
 	var (
 		err    error
+		errs   []error
 		files  []string
 		vipers map[string]*viper.Viper
 	)
@@ -153,6 +160,7 @@ func (l Loader) loadVipers() (map[string]*viper.Viper, error) {
 	}
 
 	vipers = make(map[string]*viper.Viper, len(files))
+	locations := make(map[string]string, len(files))
 	for _, file := range files {
 		var currentViper *viper.Viper
 		currentViper, err = l.loadViper(file)
@@ -160,12 +168,30 @@ func (l Loader) loadVipers() (map[string]*viper.Viper, error) {
 			return nil, err
 		}
 
-		currentName := currentViper.Get("name")
+		currentName := currentViper.GetString("name")
+		if currentName == "" && l.nameFromFilename {
+			currentName = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			slog.Debug("using filename as config name", "file", file, "config", currentName)
+			currentViper.Set("name", currentName)
+		}
+
 		if currentName == "" {
 			slog.Error("could not read config name from file", "file", file)
-			return nil, fmt.Errorf("could not read config name from file %s", file)
+			errs = append(errs, fmt.Errorf("could not read config name from file %s", file))
+			continue
+		}
+
+		if location, found := locations[currentName]; found {
+			slog.Error("duplicate config name", "config", currentName, "file", file, "existing", location)
+			errs = append(errs, fmt.Errorf("duplicate config name %s in file %s, already defined in %s", currentName, file, location))
+			continue
 		}
-		vipers[currentViper.GetString("name")] = currentViper
+		locations[currentName] = file
+		vipers[currentName] = currentViper
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 	return vipers, nil
 }
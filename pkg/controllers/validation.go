@@ -0,0 +1,220 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+const (
+	LENS_VALIDATION_CLOCK_SKEW   = 5 * time.Minute
+	LENS_VALIDATION_MAX_LIFETIME = 398 * 24 * time.Hour
+)
+
+// validateCertificate checks the certificate returned by the ACME service before it is installed on any target
+// Every failed check is returned, so the certificate is not installed on any of the installation targets
+// The certificate is validated against the domains of the order, certificates requested using a CSR use the domains of the CSR instead
+func (l Launcher) validateCertificate(cert config.Certificate, domains []string, resource *certificate.Resource) error {
+	var (
+		err  error
+		errs []error
+		leaf *x509.Certificate
+		csr  *x509.CertificateRequest
+	)
+
+	if leaf, err = certcrypto.ParsePEMCertificate(resource.Certificate); err != nil {
+		return fmt.Errorf("could not parse certificate %s with message %w", cert.Name, err)
+	}
+	slog.Debug("certificate information", "certificate", cert.Name, "cn", leaf.Subject.CommonName, "SAN", leaf.DNSNames, "serial", leaf.SerialNumber.Text(16))
+
+	// Certificates requested using a CSR do not have a private key, the key type and domains are defined by the CSR
+	if cert.Request.CsrFile != "" {
+		if csr, err = cert.Request.GetCsr(); err != nil {
+			return fmt.Errorf("could not read csr for certificate %s with message %w", cert.Name, err)
+		}
+		if err = l.validatePublicKey(leaf, csr.PublicKey); err != nil {
+			errs = append(errs, err)
+		}
+		domains = append([]string{}, csr.DNSNames...)
+		if csr.Subject.CommonName != "" {
+			domains = append(domains, csr.Subject.CommonName)
+		}
+	} else {
+		if err = l.validateKeyPair(resource); err != nil {
+			errs = append(errs, err)
+		}
+		if err = l.validateKeyType(leaf, cert.Request.GetKeyType()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err = l.validateDomains(leaf, domains); err != nil {
+		errs = append(errs, err)
+	}
+	if err = l.validateChain(cert, leaf, resource); err != nil {
+		errs = append(errs, err)
+	}
+	if err = l.validateValidity(leaf); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("validation failed for certificate %s with message %w", cert.Name, errors.Join(errs...))
+	}
+	slog.Info("certificate validated", "certificate", cert.Name, "serial", leaf.SerialNumber.Text(16))
+	return nil
+}
+
+// validateKeyPair checks that the private key returned with the certificate matches the certificate
+func (l Launcher) validateKeyPair(resource *certificate.Resource) error {
+	if _, err := tls.X509KeyPair(resource.Certificate, resource.PrivateKey); err != nil {
+		return fmt.Errorf("private key does not match certificate with message %w", err)
+	}
+	return nil
+}
+
+// validatePublicKey checks that the certificate contains the public key of the CSR
+func (l Launcher) validatePublicKey(leaf *x509.Certificate, publicKey crypto.PublicKey) error {
+	if !l.publicKeyEqual(leaf.PublicKey, publicKey) {
+		return fmt.Errorf("public key of certificate does not match csr")
+	}
+	return nil
+}
+
+// validateKeyType checks the algorithm and size of the certificate key against the requested key type
+func (l Launcher) validateKeyType(leaf *x509.Certificate, keyType certcrypto.KeyType) error {
+	var actual certcrypto.KeyType
+
+	switch key := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		actual = certcrypto.KeyType(strconv.Itoa(key.N.BitLen()))
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			actual = certcrypto.EC256
+		case elliptic.P384():
+			actual = certcrypto.EC384
+		}
+	}
+
+	if actual != keyType {
+		return fmt.Errorf("key type %s of certificate does not match requested key type %s", actual, keyType)
+	}
+	return nil
+}
+
+// validateDomains checks that the subject alternative names of the certificate are exactly the requested domains
+func (l Launcher) validateDomains(leaf *x509.Certificate, domains []string) error {
+	var (
+		requested = l.normalizeDomains(domains)
+		issued    = l.normalizeDomains(leaf.DNSNames)
+	)
+
+	if strings.Join(requested, ",") != strings.Join(issued, ",") {
+		return fmt.Errorf("subject alternative names %v of certificate do not match requested domains %v", issued, requested)
+	}
+	return nil
+}
+
+// normalizeDomains returns the unique domains in lowercase and sorted, so domain lists can be compared
+func (l Launcher) normalizeDomains(domains []string) []string {
+	var (
+		output []string
+		seen   = make(map[string]bool)
+	)
+
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if !seen[domain] {
+			seen[domain] = true
+			output = append(output, domain)
+		}
+	}
+	sort.Strings(output)
+	return output
+}
+
+// validateChain verifies the certificate using the issuer chain returned by the ACME service up to a trusted root certificate
+// The Let's Encrypt staging roots are not trusted by any system, so without rootsFile a staging certificate is verified
+// up to the top of the returned chain instead, which only checks that the chain is consistent
+func (l Launcher) validateChain(cert config.Certificate, leaf *x509.Certificate, resource *certificate.Resource) error {
+	var (
+		err     error
+		roots   *x509.CertPool
+		issuers []*x509.Certificate
+	)
+
+	if issuers, err = certcrypto.ParsePEMBundle(resource.IssuerCertificate); err != nil {
+		return fmt.Errorf("could not parse issuer certificate chain with message %w", err)
+	}
+
+	if cert.Request.Challenge.Service == config.ACME_SERVICE_LETSENCRYPT_STAGING && cert.Request.RootsFile == "" {
+		slog.Debug("no root certificates for staging service, verifying chain up to the top of the returned chain", "certificate", cert.Name, "chain", issuers[len(issuers)-1].Subject.CommonName)
+		roots = x509.NewCertPool()
+		roots.AddCert(issuers[len(issuers)-1])
+	} else if roots, err = cert.Request.GetRootCertificates(); err != nil {
+		return fmt.Errorf("could not load root certificates with message %w", err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, issuer := range issuers {
+		intermediates.AddCert(issuer)
+	}
+
+	if _, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return fmt.Errorf("could not verify certificate chain with message %w", err)
+	}
+	return nil
+}
+
+// validateValidity checks that the certificate is currently valid and does not exceed the maximum lifetime of a TLS server certificate
+func (l Launcher) validateValidity(leaf *x509.Certificate) error {
+	now := time.Now()
+
+	switch {
+	case !leaf.NotAfter.After(leaf.NotBefore):
+		return fmt.Errorf("certificate expires at %s before it becomes valid at %s", leaf.NotAfter, leaf.NotBefore)
+	case now.Add(LENS_VALIDATION_CLOCK_SKEW).Before(leaf.NotBefore):
+		return fmt.Errorf("certificate is not valid before %s", leaf.NotBefore)
+	case now.After(leaf.NotAfter):
+		return fmt.Errorf("certificate expired at %s", leaf.NotAfter)
+	case leaf.NotAfter.Sub(leaf.NotBefore) > LENS_VALIDATION_MAX_LIFETIME:
+		return fmt.Errorf("certificate lifetime from %s to %s exceeds %s", leaf.NotBefore, leaf.NotAfter, LENS_VALIDATION_MAX_LIFETIME)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+func TestLauncher_validateKeyPair(t *testing.T) {
+	var (
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+		key       = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	leaf := newTestCertificate(t, []string{"lens.example.com"}, notBefore, notAfter, key, ca, caKey)
+
+	tests := []struct {
+		name    string
+		key     any
+		wantErr bool
+	}{
+		{name: "matching key", key: key, wantErr: false},
+		{name: "key pair mismatch", key: newTestEcdsaKey(t), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := &certificate.Resource{
+				Certificate: certcrypto.PEMEncode(certcrypto.DERCertificateBytes(leaf.Raw)),
+				PrivateKey:  certcrypto.PEMEncode(tt.key),
+			}
+			if err := (Launcher{}).validateKeyPair(resource); (err != nil) != tt.wantErr {
+				t.Errorf("validateKeyPair() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLauncher_validateKeyType(t *testing.T) {
+	var (
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	rsa4096Key, err := rsa.GenerateKey(rand.Reader, 4096)
+	if err != nil {
+		t.Fatalf("could not generate rsa key: %v", err)
+	}
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate ecdsa key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		keyType certcrypto.KeyType
+		wantErr bool
+	}{
+		{name: "rsa2048", key: newTestRsaKey(t), keyType: certcrypto.RSA2048, wantErr: false},
+		{name: "rsa4096", key: rsa4096Key, keyType: certcrypto.RSA4096, wantErr: false},
+		{name: "ec256", key: newTestEcdsaKey(t), keyType: certcrypto.EC256, wantErr: false},
+		{name: "ec384", key: ec384Key, keyType: certcrypto.EC384, wantErr: false},
+		{name: "rsa size mismatch", key: newTestRsaKey(t), keyType: certcrypto.RSA4096, wantErr: true},
+		{name: "ec curve mismatch", key: newTestEcdsaKey(t), keyType: certcrypto.EC384, wantErr: true},
+		{name: "rsa instead of ec", key: newTestRsaKey(t), keyType: certcrypto.EC256, wantErr: true},
+		{name: "ec instead of rsa", key: newTestEcdsaKey(t), keyType: certcrypto.RSA2048, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := newTestCertificate(t, []string{"lens.example.com"}, notBefore, notAfter, tt.key, ca, caKey)
+			if err := (Launcher{}).validateKeyType(leaf, tt.keyType); (err != nil) != tt.wantErr {
+				t.Errorf("validateKeyType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLauncher_validateDomains(t *testing.T) {
+	var (
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	leaf := newTestCertificate(t, []string{"lens.example.com", "www.lens.example.com"}, notBefore, notAfter, newTestEcdsaKey(t), ca, caKey)
+
+	tests := []struct {
+		name    string
+		domains []string
+		wantErr bool
+	}{
+		{name: "matching domains", domains: []string{"www.lens.example.com", "lens.example.com"}, wantErr: false},
+		{name: "duplicate and uppercase domains", domains: []string{"LENS.example.com", "lens.example.com", "www.lens.example.com"}, wantErr: false},
+		{name: "missing subject alternative name", domains: []string{"lens.example.com", "www.lens.example.com", "api.lens.example.com"}, wantErr: true},
+		{name: "unexpected subject alternative name", domains: []string{"lens.example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Launcher{}).validateDomains(leaf, tt.domains); (err != nil) != tt.wantErr {
+				t.Errorf("validateDomains() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLauncher_validateValidity(t *testing.T) {
+	var (
+		now   = time.Now()
+		caKey = newTestEcdsaKey(t)
+		key   = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, now.Add(-1000*24*time.Hour), now.Add(1000*24*time.Hour), caKey, nil, nil)
+
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		wantErr   bool
+	}{
+		{name: "valid", notBefore: now.Add(-time.Hour), notAfter: now.Add(90 * 24 * time.Hour), wantErr: false},
+		{name: "maximum lifetime", notBefore: now.Add(-time.Hour), notAfter: now.Add(-time.Hour).Add(LENS_VALIDATION_MAX_LIFETIME), wantErr: false},
+		{name: "expired", notBefore: now.Add(-90 * 24 * time.Hour), notAfter: now.Add(-time.Hour), wantErr: true},
+		{name: "not yet valid", notBefore: now.Add(time.Hour), notAfter: now.Add(90 * 24 * time.Hour), wantErr: true},
+		{name: "lifetime exceeds 398 days", notBefore: now.Add(-time.Hour), notAfter: now.Add(399 * 24 * time.Hour), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := newTestCertificate(t, []string{"lens.example.com"}, tt.notBefore, tt.notAfter, key, ca, caKey)
+			if err := (Launcher{}).validateValidity(leaf); (err != nil) != tt.wantErr {
+				t.Errorf("validateValidity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLauncher_validateChain(t *testing.T) {
+	var (
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+		otherKey  = newTestEcdsaKey(t)
+		key       = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	otherCa := newTestCertificate(t, nil, notBefore, notAfter, otherKey, nil, nil)
+	leaf := newTestCertificate(t, []string{"lens.example.com"}, notBefore, notAfter, key, ca, caKey)
+
+	rootsFile := filepath.Join(t.TempDir(), "roots.pem")
+	if err := os.WriteFile(rootsFile, certcrypto.PEMEncode(certcrypto.DERCertificateBytes(ca.Raw)), 0600); err != nil {
+		t.Fatalf("could not write roots file: %v", err)
+	}
+	otherRootsFile := filepath.Join(t.TempDir(), "roots.pem")
+	if err := os.WriteFile(otherRootsFile, certcrypto.PEMEncode(certcrypto.DERCertificateBytes(otherCa.Raw)), 0600); err != nil {
+		t.Fatalf("could not write roots file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		service   string
+		rootsFile string
+		issuer    []byte
+		wantErr   bool
+	}{
+		{name: "trusted chain", service: config.ACME_SERVICE_LETSENCRYPT_PRODUCTION, rootsFile: rootsFile, issuer: ca.Raw, wantErr: false},
+		{name: "untrusted chain", service: config.ACME_SERVICE_LETSENCRYPT_PRODUCTION, rootsFile: otherRootsFile, issuer: ca.Raw, wantErr: true},
+		{name: "staging without roots file", service: config.ACME_SERVICE_LETSENCRYPT_STAGING, rootsFile: "", issuer: ca.Raw, wantErr: false},
+		{name: "staging with inconsistent chain", service: config.ACME_SERVICE_LETSENCRYPT_STAGING, rootsFile: "", issuer: otherCa.Raw, wantErr: true},
+		{name: "staging with untrusted roots file", service: config.ACME_SERVICE_LETSENCRYPT_STAGING, rootsFile: otherRootsFile, issuer: ca.Raw, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := config.Certificate{
+				Name: "test",
+				Request: config.Request{
+					Challenge: config.Challenge{Service: tt.service},
+					RootsFile: tt.rootsFile,
+				},
+			}
+			resource := &certificate.Resource{
+				IssuerCertificate: certcrypto.PEMEncode(certcrypto.DERCertificateBytes(tt.issuer)),
+			}
+			if err := (Launcher{}).validateChain(cert, leaf, resource); (err != nil) != tt.wantErr {
+				t.Errorf("validateChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLauncher_validateCertificate(t *testing.T) {
+	var (
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+		key       = newTestEcdsaKey(t)
+	)
+
+	// The .invalid domains never resolve, so validation fails when the DNS lookups of the request are repeated
+	domains := []string{"lens.invalid", "www.lens.invalid"}
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	leaf := newTestCertificate(t, domains, notBefore, notAfter, key, ca, caKey)
+
+	cert := config.Certificate{
+		Name: "test",
+		Request: config.Request{
+			Challenge: config.Challenge{Service: config.ACME_SERVICE_LETSENCRYPT_STAGING},
+			KeyType:   config.ACME_KEY_TYPE_EC256,
+			Content:   config.Content{CommonName: domains[0], SubjectAlternativeNames: domains[1:]},
+		},
+	}
+	resource := &certificate.Resource{
+		Certificate:       certcrypto.PEMEncode(certcrypto.DERCertificateBytes(leaf.Raw)),
+		IssuerCertificate: certcrypto.PEMEncode(certcrypto.DERCertificateBytes(ca.Raw)),
+		PrivateKey:        certcrypto.PEMEncode(key),
+	}
+
+	tests := []struct {
+		name    string
+		domains []string
+		wantErr bool
+	}{
+		{name: "domains of the order", domains: domains, wantErr: false},
+		{name: "domains of the order do not match", domains: domains[:1], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (Launcher{}).validateCertificate(cert, tt.domains, resource); (err != nil) != tt.wantErr {
+				t.Errorf("validateCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+const (
+	LENS_VERIFICATION_TIMEOUT = 10 * time.Second
+)
+
+// VerificationError reports a virtual server which does not serve the installed certificate for a server name
+type VerificationError struct {
+	Target     config.Target
+	VServer    string
+	Address    string
+	ServerName string
+	Err        error
+}
+
+func (e VerificationError) Error() string {
+	return fmt.Sprintf("could not verify certificate on vserver %s at %s for server name %s in organization %s environment %s with message %s", e.VServer, e.Address, e.ServerName, e.Target.Organization, e.Target.Environment, e.Err)
+}
+
+func (e VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// verifyInstallation connects to every ssl vserver of the installation with a verification address,
+// once for each domain of the certificate as server name, and checks that the installed certificate and its issuer are served
+// The vservers in skip are not verified, as the certificate could not be bound to them
+func (l Launcher) verifyInstallation(i config.Installation, r config.Certificate, skip map[string]bool) error {
+	var (
+		err    error
+		errs   []error
+		leaf   *x509.Certificate
+		issuer *x509.Certificate
+		chain  [][]byte
+	)
+
+	if leaf, err = certcrypto.ParsePEMCertificate(r.Resource.Certificate); err != nil {
+		return fmt.Errorf("could not parse certificate %s for verification with message %w", r.Name, err)
+	}
+	if chain, err = l.getIssuerChain(r.Resource); err != nil {
+		return fmt.Errorf("could not get issuer certificate chain for certificate %s with message %w", r.Name, err)
+	}
+	if issuer, err = certcrypto.ParsePEMCertificate(chain[0]); err != nil {
+		return fmt.Errorf("could not parse issuer certificate for certificate %s with message %w", r.Name, err)
+	}
+
+	for _, vserver := range i.SslVirtualServers {
+		if vserver.Verify.Address == "" {
+			continue
+		}
+		if skip[vserver.Name] {
+			slog.Warn("skipping verification of vserver with failed binding", "target", i.Target, "certificate", r.Name, "vserver", vserver.Name)
+			continue
+		}
+
+		address := net.JoinHostPort(vserver.Verify.Address, strconv.Itoa(vserver.Verify.GetPort()))
+		for _, serverName := range l.getVerificationServerNames(leaf) {
+			slog.Debug("verify served certificate", "target", i.Target, "certificate", r.Name, "vserver", vserver.Name, "address", address, "servername", serverName)
+			if err = l.verifyServedCertificate(address, serverName, leaf, issuer); err != nil {
+				slog.Error("vserver does not serve installed certificate", "target", i.Target, "certificate", r.Name, "vserver", vserver.Name, "address", address, "servername", serverName, "error", err)
+				errs = append(errs, VerificationError{Target: i.Target, VServer: vserver.Name, Address: address, ServerName: serverName, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyServedCertificate performs a TLS handshake and compares the served certificate chain with the installed certificate
+// The chain is not verified against the system roots, so certificates from staging environments can be verified as well
+func (l Launcher) verifyServedCertificate(address string, serverName string, leaf *x509.Certificate, issuer *x509.Certificate) error {
+	var (
+		err       error
+		conn      *tls.Conn
+		tlsConfig *tls.Config
+	)
+
+	if tlsConfig, err = l.getVerificationTlsConfig(serverName, leaf); err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: LENS_VERIFICATION_TIMEOUT}
+	if conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig); err != nil {
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	defer conn.Close()
+
+	served := conn.ConnectionState().PeerCertificates
+	if len(served) == 0 {
+		return fmt.Errorf("no certificate served")
+	}
+	if !served[0].Equal(leaf) {
+		return fmt.Errorf("served certificate with serial %s instead of %s", served[0].SerialNumber.Text(16), leaf.SerialNumber.Text(16))
+	}
+	if len(served) < 2 || !served[1].Equal(issuer) {
+		return fmt.Errorf("served certificate chain does not include issuer %s", issuer.Subject.CommonName)
+	}
+	return nil
+}
+
+// getVerificationTlsConfig only offers the cipher suites for the key type of the certificate,
+// so a virtual server with both an RSA and an ECDSA certificate bound serves the certificate which is verified
+// TLS 1.3 cipher suites do not depend on the key type, so the handshake is limited to TLS 1.2
+func (l Launcher) getVerificationTlsConfig(serverName string, leaf *x509.Certificate) (*tls.Config, error) {
+	output := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	}
+
+	switch leaf.PublicKeyAlgorithm {
+	case x509.RSA:
+		output.CipherSuites = []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		}
+	case x509.ECDSA:
+		output.CipherSuites = []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %s for verification", leaf.PublicKeyAlgorithm)
+	}
+	return output, nil
+}
+
+// getVerificationServerNames returns the domains of the certificate to use as server name
+// A wildcard domain is verified using a host directly below the wildcard
+func (l Launcher) getVerificationServerNames(leaf *x509.Certificate) []string {
+	var output []string
+
+	for _, domain := range leaf.DNSNames {
+		output = append(output, strings.Replace(domain, "*", "lens-verification", 1))
+	}
+	if len(output) == 0 && leaf.Subject.CommonName != "" {
+		output = append(output, leaf.Subject.CommonName)
+	}
+	return output
+}
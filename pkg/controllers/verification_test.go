@@ -0,0 +1,246 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/certificate"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/models/config"
+)
+
+// newTestCertificate creates a certificate for the domains signed by parent, or a self-signed CA certificate when parent is nil
+func newTestCertificate(t *testing.T, domains []string, notBefore time.Time, notAfter time.Time, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("could not generate serial: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     domains,
+	}
+	if len(domains) > 0 {
+		template.Subject = pkix.Name{CommonName: domains[0]}
+	}
+	if parent == nil {
+		template.Subject = pkix.Name{CommonName: "LENS Test CA"}
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %v", err)
+	}
+	return cert
+}
+
+func newTestRsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate rsa key: %v", err)
+	}
+	return key
+}
+
+func newTestEcdsaKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate ecdsa key: %v", err)
+	}
+	return key
+}
+
+func TestLauncher_verifyServedCertificate(t *testing.T) {
+	var (
+		domains   = []string{"lens.example.com"}
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+		rsaKey    = newTestRsaKey(t)
+		ecdsaKey  = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	rsaLeaf := newTestCertificate(t, domains, notBefore, notAfter, rsaKey, ca, caKey)
+	ecdsaLeaf := newTestCertificate(t, domains, notBefore, notAfter, ecdsaKey, ca, caKey)
+	otherLeaf := newTestCertificate(t, domains, notBefore, notAfter, newTestRsaKey(t), ca, caKey)
+
+	// The ECDSA certificate is listed first, so it is served to every client which supports it
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{
+			{Certificate: [][]byte{ecdsaLeaf.Raw, ca.Raw}, PrivateKey: ecdsaKey},
+			{Certificate: [][]byte{rsaLeaf.Raw, ca.Raw}, PrivateKey: rsaKey},
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		leaf    *x509.Certificate
+		wantErr bool
+	}{
+		{name: "rsa certificate", leaf: rsaLeaf, wantErr: false},
+		{name: "ecdsa certificate", leaf: ecdsaLeaf, wantErr: false},
+		{name: "certificate not served", leaf: otherLeaf, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Launcher{}.verifyServedCertificate(server.Listener.Addr().String(), domains[0], tt.leaf, ca)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyServedCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newTestTlsServer returns a TLS server serving the certificate chain
+func newTestTlsServer(t *testing.T, key crypto.Signer, chain ...*x509.Certificate) *httptest.Server {
+	t.Helper()
+
+	var raw [][]byte
+	for _, cert := range chain {
+		raw = append(raw, cert.Raw)
+	}
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: raw, PrivateKey: key}}}
+	server.StartTLS()
+	return server
+}
+
+// addTestSslVirtualServer adds a vserver to the installation which is verified at the address of the server
+func addTestSslVirtualServer(t *testing.T, i *config.Installation, name string, server *httptest.Server) {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("could not parse server address: %v", err)
+	}
+	i.AddSslVirtualServer(name, false)
+	i.SslVirtualServers[len(i.SslVirtualServers)-1].Verify.Address = host
+	i.SslVirtualServers[len(i.SslVirtualServers)-1].Verify.Port, _ = strconv.Atoi(port)
+}
+
+func TestLauncher_saveInstallation(t *testing.T) {
+	var (
+		domains   = []string{"lens.example.com"}
+		notBefore = time.Now().Add(-time.Hour)
+		notAfter  = time.Now().Add(24 * time.Hour)
+		caKey     = newTestEcdsaKey(t)
+		leafKey   = newTestEcdsaKey(t)
+		otherKey  = newTestEcdsaKey(t)
+	)
+
+	ca := newTestCertificate(t, nil, notBefore, notAfter, caKey, nil, nil)
+	leaf := newTestCertificate(t, domains, notBefore, notAfter, leafKey, ca, caKey)
+	otherLeaf := newTestCertificate(t, domains, notBefore, notAfter, otherKey, ca, caKey)
+
+	// VS_A serves the installed certificate, VS_B still serves the previous certificate
+	installed := newTestTlsServer(t, leafKey, leaf, ca)
+	defer installed.Close()
+	previous := newTestTlsServer(t, otherKey, otherLeaf, ca)
+	defer previous.Close()
+
+	r := config.Certificate{
+		Name: "test",
+		Resource: &certificate.Resource{
+			Certificate:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}),
+			IssuerCertificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}),
+		},
+	}
+	bindingErr := BindingError{CertKeyName: fakeNitroCertKeyName, Endpoint: BINDING_ENDPOINT_VSERVER, Name: "VS_B", Err: errors.New("binding failed")}
+
+	tests := []struct {
+		name                string
+		vservers            []string
+		installErr          error
+		wantSaved           int
+		wantBindingErrors   bool
+		wantVerificationErr bool
+	}{
+		{name: "verified", vservers: []string{"VS_A"}, installErr: nil, wantSaved: 1},
+		{name: "binding errors only verify remaining vservers", vservers: []string{"VS_A", "VS_B"}, installErr: errors.Join(bindingErr), wantSaved: 1, wantBindingErrors: true},
+		{name: "verification failure is not saved", vservers: []string{"VS_A", "VS_B"}, installErr: nil, wantSaved: 0, wantVerificationErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var i config.Installation
+			for _, vserver := range tt.vservers {
+				if vserver == "VS_A" {
+					addTestSslVirtualServer(t, &i, vserver, installed)
+				} else {
+					addTestSslVirtualServer(t, &i, vserver, previous)
+				}
+			}
+
+			f := newFakeNitro(nil, nil)
+			server := httptest.NewServer(f)
+			defer server.Close()
+
+			err := Launcher{}.saveInstallation(newTestNitroClient(t, server), i, r, tt.installErr)
+			if got := f.getSaved(); got != tt.wantSaved {
+				t.Errorf("saveInstallation() saved = %d, want %d", got, tt.wantSaved)
+			}
+			if got := onlyBindingErrors(err); got != tt.wantBindingErrors {
+				t.Errorf("saveInstallation() error = %v, want only binding errors %v", err, tt.wantBindingErrors)
+			}
+			var verificationErr VerificationError
+			if got := errors.As(err, &verificationErr); got != tt.wantVerificationErr {
+				t.Errorf("saveInstallation() error = %v, want verification errors %v", err, tt.wantVerificationErr)
+			}
+		})
+	}
+}
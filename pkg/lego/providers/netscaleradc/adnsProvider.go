@@ -40,7 +40,8 @@ type ADnsProvider struct {
 }
 
 // NewADnsProvider returns an Authoritative DNS Provider from a configured list of hosts
-func NewADnsProvider(e registry.Environment, maxRetries int) (*ADnsProvider, error) {
+// The TXT records are created in the admin partition, or in the default partition if partition is empty
+func NewADnsProvider(e registry.Environment, partition string, maxRetries int) (*ADnsProvider, error) {
 	var (
 		err error
 		c   *nitro.Client
@@ -54,6 +55,11 @@ func NewADnsProvider(e registry.Environment, maxRetries int) (*ADnsProvider, err
 		return nil, fmt.Errorf("ns acme %s provider initialization from configuration failed: %w", ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, err)
 	}
 
+	if err = SwitchPartition(c, partition); err != nil {
+		slog.Error("ns acme provider: partition switch failed", "provider", ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, "environment", e.Name, "partition", partition, "error", err)
+		return nil, fmt.Errorf("ns acme %s provider initialization from configuration failed: %w", ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, err)
+	}
+
 	p = &ADnsProvider{
 		client:     c,
 		maxRetries: maxRetries,
@@ -65,7 +71,7 @@ func NewADnsProvider(e registry.Environment, maxRetries int) (*ADnsProvider, err
 }
 
 // NewADnsProvider returns an Authoritative DNS Provider from environment variable settings
-func NewADnsProviderFromEnv(maxRetries int) (*ADnsProvider, error) {
+func NewADnsProviderFromEnv(partition string, maxRetries int) (*ADnsProvider, error) {
 	var (
 		err error
 		c   *Config
@@ -86,6 +92,11 @@ func NewADnsProviderFromEnv(maxRetries int) (*ADnsProvider, error) {
 		return nil, err
 	}
 
+	if err = SwitchPartition(n, partition); err != nil {
+		slog.Error("ns acme provider: partition switch failed", "provider", ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, "environment", "os", "partition", partition, "error", err)
+		return nil, err
+	}
+
 	p = &ADnsProvider{
 		client:     n,
 		maxRetries: maxRetries,
@@ -35,6 +35,18 @@ const (
 	EnvTimeout                   = envNamespace + "TIMEOUT"
 )
 
+// ProviderOptions holds the options of the NetScaler ADC challenge providers in the certificate configuration
+type ProviderOptions struct {
+	MaxRetries int `json:"maxRetries" yaml:"maxRetries" mapstructure:"maxRetries"`
+}
+
+// NewProviderOptions returns the default options of the NetScaler ADC challenge providers
+func NewProviderOptions() ProviderOptions {
+	return ProviderOptions{
+		MaxRetries: 10,
+	}
+}
+
 type Config struct {
 	Name                      string
 	Address                   string
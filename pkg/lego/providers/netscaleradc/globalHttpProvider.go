@@ -47,7 +47,8 @@ type GlobalHttpProvider struct {
 }
 
 // NewGlobalHttpProvider returns a HTTPProvider instance with a configured list of hosts
-func NewGlobalHttpProvider(e registry.Environment, maxRetries int, timestamp string) (*GlobalHttpProvider, error) {
+// The responder configuration is created in the admin partition, or in the default partition if partition is empty
+func NewGlobalHttpProvider(e registry.Environment, partition string, maxRetries int, timestamp string) (*GlobalHttpProvider, error) {
 	var (
 		err error
 		c   *nitro.Client
@@ -61,6 +62,11 @@ func NewGlobalHttpProvider(e registry.Environment, maxRetries int, timestamp str
 		return nil, fmt.Errorf("ns acme %s provider initialization from configuration failed: %w", ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, err)
 	}
 
+	if err = SwitchPartition(c, partition); err != nil {
+		slog.Error("ns acme provider: partition switch failed", "provider", ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, "environment", e.Name, "partition", partition, "error", err)
+		return nil, fmt.Errorf("ns acme %s provider initialization from configuration failed: %w", ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, err)
+	}
+
 	p = &GlobalHttpProvider{
 		client:     c,
 		maxRetries: maxRetries,
@@ -73,7 +79,7 @@ func NewGlobalHttpProvider(e registry.Environment, maxRetries int, timestamp str
 }
 
 // NewGlobalHttpProvider returns an HTTPProvider instance from environment variable settings
-func NewGlobalHttpProviderFromEnv(maxRetries int, timestamp string) (*GlobalHttpProvider, error) {
+func NewGlobalHttpProviderFromEnv(partition string, maxRetries int, timestamp string) (*GlobalHttpProvider, error) {
 	var (
 		err error
 		c   *Config
@@ -94,6 +100,11 @@ func NewGlobalHttpProviderFromEnv(maxRetries int, timestamp string) (*GlobalHttp
 		return nil, err
 	}
 
+	if err = SwitchPartition(n, partition); err != nil {
+		slog.Error("ns acme provider: partition switch failed", "provider", ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, "environment", "os", "partition", partition, "error", err)
+		return nil, err
+	}
+
 	p = &GlobalHttpProvider{
 		client:     n,
 		maxRetries: maxRetries,
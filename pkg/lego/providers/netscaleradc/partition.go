@@ -0,0 +1,46 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package netscaleradc
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro"
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/controllers"
+)
+
+// SwitchPartition switches the NITRO session of the client to an admin partition
+// The partition is bound to the session, so the client logs in before switching.
+// An empty partition name leaves the client in the default partition.
+func SwitchPartition(c *nitro.Client, partition string) error {
+	var err error
+
+	if partition == "" {
+		return nil
+	}
+
+	slog.Debug("switching nitro session to partition", "client", c.Name, "partition", partition)
+	if err = c.Login(); err != nil {
+		return fmt.Errorf("could not login to switch to partition %s with message %w", partition, err)
+	}
+
+	if _, err = controllers.NewNsPartitionController(c).Switch(partition); err != nil {
+		return fmt.Errorf("could not switch to partition %s with message %w", partition, err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package netscaleradc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/nitro/resource/controllers"
+	"github.com/go-acme/lego/v4/challenge"
+)
+
+const (
+	// redirectHttpProviderLogLabel identifies the provider in log messages, the provider is configured through the webserver provider and is not registered itself
+	redirectHttpProviderLogLabel = "netscaler-http-redirect"
+)
+
+// RedirectHttpProvider forwards ACME HTTP-01 requests arriving on NetScaler ADC to a webserver running on the lens host
+// NetScaler ADC fetches the key authorization from the webserver using an HTTP callout, which is returned by a globally bound responder policy
+type RedirectHttpProvider struct {
+	*GlobalHttpProvider
+	upstream challenge.Provider
+
+	htcController *controllers.PolicyHttpCalloutController
+	htcPrefix     string
+
+	address string
+	port    int
+}
+
+// NewRedirectHttpProvider returns a RedirectHttpProvider which forwards requests to upstream, listening on address and port
+//
+//	p is the provider used to manage the responder configuration on NetScaler ADC
+//	upstream is the webserver serving the key authorization on the lens host
+//	address is the address on which NetScaler ADC can reach the webserver
+func NewRedirectHttpProvider(p *GlobalHttpProvider, upstream challenge.Provider, address string, port string) (*RedirectHttpProvider, error) {
+	var (
+		err error
+		n   int
+	)
+
+	if address == "" {
+		return nil, fmt.Errorf("ns acme %s provider initialization failed: missing address of the webserver", redirectHttpProviderLogLabel)
+	}
+
+	if n, err = strconv.Atoi(port); err != nil {
+		return nil, fmt.Errorf("ns acme %s provider initialization failed: invalid port %s: %w", redirectHttpProviderLogLabel, port, err)
+	}
+
+	return &RedirectHttpProvider{
+		GlobalHttpProvider: p,
+		upstream:           upstream,
+		htcController:      controllers.NewPolicyHttpCalloutController(p.client),
+		htcPrefix:          "HTC_LENS_",
+		address:            address,
+		port:               n,
+	}, nil
+}
+
+// Present the ACME challenge on the webserver and configure NetScaler ADC to forward the ACME request before validation
+//
+//	domain is the fqdn for which the challenge will be provided
+//	token is the path to which ACME will look  for the challenge (/.well-known/acme-challenge/<token>)
+//	keyAuth is the value which must be returned for a successful challenge
+func (p *RedirectHttpProvider) Present(domain string, token string, keyAuth string) error {
+	var err error
+	slog.Info("ns acme request: start", "provider", redirectHttpProviderLogLabel, "domain", domain)
+
+	if err = p.upstream.Present(domain, token, keyAuth); err != nil {
+		slog.Error("ns acme request: could not present challenge on webserver", "provider", redirectHttpProviderLogLabel, "domain", domain, "error", err)
+		return fmt.Errorf("ns acme request: could not present challenge on webserver for %s: %w", domain, err)
+	}
+
+	if err = p.configure(domain, token); err != nil {
+		// Do not leave the webserver running when NetScaler ADC cannot forward the ACME request
+		if cleanupErr := p.upstream.CleanUp(domain, token, keyAuth); cleanupErr != nil {
+			slog.Error("ns acme request: could not clean up challenge on webserver", "provider", redirectHttpProviderLogLabel, "domain", domain, "error", cleanupErr)
+		}
+		return err
+	}
+
+	slog.Debug("ns acme request: completed", "provider", redirectHttpProviderLogLabel, "domain", domain)
+	return nil
+}
+
+// CleanUp the ACME challenge on NetScaler ADC and the webserver after validation
+//
+//	domain is the fqdn for which the challenge will be provided
+//	token is the path to which ACME will look  for the challenge (/.well-known/acme-challenge/<token>)
+//	keyAuth is the value which must be returned for a successful challenge
+func (p *RedirectHttpProvider) CleanUp(domain string, token string, keyAuth string) error {
+	var err error
+	slog.Info("ns acme cleanup: start", "provider", redirectHttpProviderLogLabel, "domain", domain)
+
+	// Remove the responder configuration before removing the HTTP callout it references
+	if err = p.GlobalHttpProvider.CleanUp(domain, token, keyAuth); err == nil {
+		htcCalloutName := p.getHttpCalloutName(domain)
+		slog.Debug("ns acme cleanup: remove http callout", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", htcCalloutName)
+		if _, err = p.htcController.Delete(htcCalloutName); err != nil {
+			slog.Error("ns acme cleanup: could not remove http callout", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", htcCalloutName)
+			err = fmt.Errorf("ns acme cleanup: could not remove http callout %s for %s: %w", htcCalloutName, domain, err)
+		}
+	}
+
+	// Always stop the webserver, even if the configuration on NetScaler ADC could not be removed
+	if upstreamErr := p.upstream.CleanUp(domain, token, keyAuth); upstreamErr != nil {
+		slog.Error("ns acme cleanup: could not clean up challenge on webserver", "provider", redirectHttpProviderLogLabel, "domain", domain, "error", upstreamErr)
+		err = errors.Join(err, fmt.Errorf("ns acme cleanup: could not clean up challenge on webserver for %s: %w", domain, upstreamErr))
+	}
+
+	if err != nil {
+		return err
+	}
+	slog.Debug("ns acme cleanup: completed", "provider", redirectHttpProviderLogLabel, "domain", domain)
+	return nil
+}
+
+// configure creates the HTTP callout to the webserver and the responder configuration returning its response
+// If any step fails, the resources created in the previous steps are removed again
+func (p *RedirectHttpProvider) configure(domain string, token string) error {
+	var (
+		err     error
+		cleanup []func()
+	)
+
+	htcCalloutName := p.getHttpCalloutName(domain)
+	rsaActionName := p.getResponderActionName(domain)
+	rspPolicyName := p.getResponderPolicyName(domain)
+	htcHostExpr := "\"" + domain + "\""
+	htcUrlStemExpr := "\"/.well-known/acme-challenge/" + token + "\""
+	rsaAction := "\"HTTP/1.1 200 OK\\r\\n\\r\\n\" + SYS.HTTP_CALLOUT(" + htcCalloutName + ")"
+	rspRule := "HTTP.REQ.HOSTNAME.EQ(\"" + domain + "\") && HTTP.REQ.URL.EQ(\"/.well-known/acme-challenge/" + token + "\")"
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		// Remove the resources in reverse order, as the responder configuration references the HTTP callout
+		for n := len(cleanup) - 1; n >= 0; n-- {
+			cleanup[n]()
+		}
+	}()
+
+	// Create HTTP callout to the webserver, passing the domain in the Host header to match the challenge
+	slog.Debug("ns acme request: create http callout", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", htcCalloutName, "address", p.address, "port", p.port)
+	if _, err = p.htcController.Add(htcCalloutName, p.address, p.port, "GET", htcHostExpr, htcUrlStemExpr, "TEXT", "HTTP.RES.BODY(1024)"); err != nil {
+		slog.Error("ns acme request: could not create http callout", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", htcCalloutName)
+		return fmt.Errorf("ns acme request: could not create http callout %s for %s: %w", htcCalloutName, domain, err)
+	}
+	cleanup = append(cleanup, func() {
+		slog.Debug("ns acme request: remove http callout", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", htcCalloutName)
+		if _, cleanupErr := p.htcController.Delete(htcCalloutName); cleanupErr != nil {
+			slog.Error("ns acme request: could not remove http callout", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", htcCalloutName, "error", cleanupErr)
+		}
+	})
+
+	// Create responder action
+	slog.Debug("ns acme request: create responder action", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rsaActionName)
+	if _, err = p.rsaController.Add(rsaActionName, "respondwith", rsaAction); err != nil {
+		slog.Error("ns acme request: could not create responder action", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rsaActionName)
+		return fmt.Errorf("ns acme request: could not create responder action %s for %s: %w", rsaActionName, domain, err)
+	}
+	cleanup = append(cleanup, func() {
+		slog.Debug("ns acme request: remove responder action", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rsaActionName)
+		if _, cleanupErr := p.rsaController.Delete(rsaActionName); cleanupErr != nil {
+			slog.Error("ns acme request: could not remove responder action", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rsaActionName, "error", cleanupErr)
+		}
+	})
+
+	// Create responder policy
+	slog.Debug("ns acme request: create responder policy", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rspPolicyName)
+	if _, err = p.rspController.Add(rspPolicyName, rspRule, rsaActionName, ""); err != nil {
+		slog.Error("ns acme request: could not create responder policy", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rspPolicyName)
+		return fmt.Errorf("ns acme request: could not create responder policy %s for %s: %w", rspPolicyName, domain, err)
+	}
+	cleanup = append(cleanup, func() {
+		slog.Debug("ns acme request: remove responder policy", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rspPolicyName)
+		if _, cleanupErr := p.rspController.Delete(rspPolicyName); cleanupErr != nil {
+			slog.Error("ns acme request: could not remove responder policy", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rspPolicyName, "error", cleanupErr)
+		}
+	})
+
+	// Bind responder policy to global REQ_OVERRIDE
+	if err = p.bindResponderPolicy(domain); err != nil {
+		slog.Error("ns acme request: could not bind global responder policy", "provider", redirectHttpProviderLogLabel, "domain", domain, "resource", rspPolicyName)
+		return fmt.Errorf("ns acme request: could not bind global responder policy %s for %s: %w", rspPolicyName, domain, err)
+	}
+	return nil
+}
+
+// getHttpCalloutName generates the name for the HTTP callout
+func (p *RedirectHttpProvider) getHttpCalloutName(domain string) string {
+	return p.htcPrefix + domain + "_" + p.timestamp
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package providers
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Constructor creates a challenge provider for an environment using the typed options from the certificate configuration
+// Partition is the admin partition of the request target, an empty partition refers to the default partition
+type Constructor[T any] func(environment registry.Environment, partition string, timestamp string, options T) (challenge.Provider, error)
+
+// Provider describes a challenge provider which can be referenced by name in the certificate configuration
+type Provider struct {
+	Name           string
+	ChallengeTypes []string
+
+	constructor func(environment registry.Environment, partition string, timestamp string, options map[string]interface{}) (challenge.Provider, error)
+}
+
+var (
+	providersMutex = &sync.RWMutex{}
+	providers      = make(map[string]Provider)
+)
+
+// Register makes a challenge provider available by name for the given challenge types
+// The options in the certificate configuration are decoded into a copy of defaults before calling the constructor
+// If Register is called twice with the same name, it panics
+func Register[T any](name string, challengeTypes []string, defaults T, constructor Constructor[T]) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+
+	if _, found := providers[name]; found {
+		panic("challenge provider " + name + " is already registered")
+	}
+
+	slog.Debug("registering challenge provider", "provider", name, "types", challengeTypes)
+	providers[name] = Provider{
+		Name:           name,
+		ChallengeTypes: challengeTypes,
+		constructor: func(environment registry.Environment, partition string, timestamp string, options map[string]interface{}) (challenge.Provider, error) {
+			var (
+				err     error
+				decoder *mapstructure.Decoder
+				output  = defaults
+			)
+
+			decoder, err = mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+				ErrorUnused:      true,
+				WeaklyTypedInput: true,
+				Result:           &output,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			if err = decoder.Decode(options); err != nil {
+				return nil, fmt.Errorf("invalid options for challenge provider %s: %w", name, err)
+			}
+			return constructor(environment, partition, timestamp, output)
+		},
+	}
+}
+
+// Get returns the challenge provider registered with name
+func Get(name string) (Provider, bool) {
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+
+	p, found := providers[name]
+	return p, found
+}
+
+// SupportsChallengeType reports whether the provider can solve challenges of type t
+func (p Provider) SupportsChallengeType(t string) bool {
+	for _, v := range p.ChallengeTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// NewChallengeProvider creates a challenge provider for an environment using the options from the certificate configuration
+func (p Provider) NewChallengeProvider(environment registry.Environment, partition string, timestamp string, options map[string]interface{}) (challenge.Provider, error) {
+	return p.constructor(environment, partition, timestamp, options)
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package webserver
+
+const (
+	ACME_CHALLENGE_PROVIDER_WEBSERVER = "webserver"
+
+	// WEBSERVER_DEFAULT_PORT is the port on which the webserver listens when no port is configured
+	WEBSERVER_DEFAULT_PORT = "12346"
+)
+
+// ProviderOptions holds the options of the webserver challenge provider in the certificate configuration
+type ProviderOptions struct {
+	Address    string `json:"address" yaml:"address" mapstructure:"address"`
+	Port       string `json:"port" yaml:"port" mapstructure:"port"`
+	HostHeader string `json:"hostHeader" yaml:"hostHeader" mapstructure:"hostHeader"`
+	Shared     bool   `json:"shared" yaml:"shared" mapstructure:"shared"`
+
+	// Redirect configures NetScaler ADC in the target environment to forward ACME requests to the webserver
+	Redirect        bool   `json:"redirect" yaml:"redirect" mapstructure:"redirect"`
+	RedirectAddress string `json:"redirectAddress" yaml:"redirectAddress" mapstructure:"redirectAddress"`
+	MaxRetries      int    `json:"maxRetries" yaml:"maxRetries" mapstructure:"maxRetries"`
+}
+
+// NewProviderOptions returns the default options of the webserver challenge provider
+func NewProviderOptions() ProviderOptions {
+	return ProviderOptions{
+		Address:    "",
+		Port:       WEBSERVER_DEFAULT_PORT,
+		HostHeader: "",
+		Shared:     false,
+		Redirect:   false,
+		MaxRetries: 10,
+	}
+}
+
+// GetPort returns the configured port, defaulting to WEBSERVER_DEFAULT_PORT
+func (o ProviderOptions) GetPort() string {
+	if o.Port == "" {
+		return WEBSERVER_DEFAULT_PORT
+	}
+	return o.Port
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package webserver
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+
+	"github.com/go-acme/lego/v4/challenge/http01"
+)
+
+var (
+	listenersMutex = &sync.Mutex{}
+	listeners      = make(map[string]*sharedListener)
+)
+
+// SharedHttpProvider serves HTTP-01 challenges for concurrent ACME requests from a single listener per address
+// The listener is opened when the first challenge is presented and closed when the last challenge is cleaned up
+type SharedHttpProvider struct {
+	address    string
+	hostHeader string
+}
+
+// NewSharedHttpProvider returns a SharedHttpProvider listening on address and port, port defaults to WEBSERVER_DEFAULT_PORT
+// hostHeader is the name of the header which must match the domain of the challenge, which defaults to the Host header
+func NewSharedHttpProvider(address string, port string, hostHeader string) *SharedHttpProvider {
+	if port == "" {
+		port = WEBSERVER_DEFAULT_PORT
+	}
+	return &SharedHttpProvider{
+		address:    net.JoinHostPort(address, port),
+		hostHeader: textproto.CanonicalMIMEHeaderKey(hostHeader),
+	}
+}
+
+// Present the ACME challenge on the shared listener before validation
+//
+//	domain is the fqdn for which the challenge will be provided
+//	token is the path to which ACME will look  for the challenge (/.well-known/acme-challenge/<token>)
+//	keyAuth is the value which must be returned for a successful challenge
+func (p *SharedHttpProvider) Present(domain string, token string, keyAuth string) error {
+	var (
+		err   error
+		found bool
+		l     *sharedListener
+	)
+	slog.Info("acme request: start", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address)
+
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	if l, found = listeners[p.address]; !found {
+		slog.Debug("acme request: open shared listener", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address)
+		if l, err = newSharedListener(p.address); err != nil {
+			slog.Error("acme request: could not open shared listener", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address, "error", err)
+			return fmt.Errorf("acme request: could not open shared listener on %s for %s: %w", p.address, domain, err)
+		}
+		listeners[p.address] = l
+	}
+
+	l.add(token, sharedChallenge{
+		domain:     domain,
+		keyAuth:    keyAuth,
+		hostHeader: p.hostHeader,
+	})
+
+	slog.Debug("acme request: completed", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain)
+	return nil
+}
+
+// CleanUp the ACME challenge on the shared listener after validation
+//
+//	domain is the fqdn for which the challenge will be provided
+//	token is the path to which ACME will look  for the challenge (/.well-known/acme-challenge/<token>)
+//	keyAuth is the value which must be returned for a successful challenge
+func (p *SharedHttpProvider) CleanUp(domain string, token string, keyAuth string) error {
+	var (
+		found bool
+		l     *sharedListener
+	)
+	slog.Info("acme cleanup: start", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address)
+
+	listenersMutex.Lock()
+	defer listenersMutex.Unlock()
+
+	if l, found = listeners[p.address]; !found {
+		slog.Debug("acme cleanup: no shared listener found", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address)
+		return nil
+	}
+
+	// Keep the listener open as long as other ACME requests have pending challenges
+	if l.remove(token) > 0 {
+		slog.Debug("acme cleanup: completed", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain)
+		return nil
+	}
+
+	slog.Debug("acme cleanup: close shared listener", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address)
+	delete(listeners, p.address)
+	if err := l.server.Close(); err != nil {
+		slog.Error("acme cleanup: could not close shared listener", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain, "address", p.address, "error", err)
+		return fmt.Errorf("acme cleanup: could not close shared listener on %s for %s: %w", p.address, domain, err)
+	}
+
+	slog.Debug("acme cleanup: completed", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", domain)
+	return nil
+}
+
+type sharedChallenge struct {
+	domain     string
+	keyAuth    string
+	hostHeader string
+}
+
+// matches validates the incoming request to prevent DNS rebind attacks
+func (c sharedChallenge) matches(r *http.Request) bool {
+	var host string
+	switch c.hostHeader {
+	case "", "Host":
+		host = r.Host
+	case "Forwarded":
+		host = getForwardedHost(r.Header.Get(c.hostHeader))
+	default:
+		host = r.Header.Get(c.hostHeader)
+	}
+	return strings.HasPrefix(host, c.domain)
+}
+
+type sharedListener struct {
+	server     *http.Server
+	mutex      *sync.RWMutex
+	challenges map[string]sharedChallenge
+}
+
+func newSharedListener(address string) (*sharedListener, error) {
+	var (
+		err      error
+		listener net.Listener
+	)
+
+	if listener, err = net.Listen("tcp", address); err != nil {
+		return nil, err
+	}
+
+	l := &sharedListener{
+		mutex:      &sync.RWMutex{},
+		challenges: make(map[string]sharedChallenge),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(http01.ChallengePath(""), l.serve)
+	l.server = &http.Server{Handler: mux}
+	l.server.SetKeepAlivesEnabled(false)
+
+	go func() {
+		if err := l.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("acme request: shared listener stopped", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "address", address, "error", err)
+		}
+	}()
+	return l, nil
+}
+
+func (l *sharedListener) add(token string, c sharedChallenge) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.challenges[token] = c
+}
+
+// remove deletes the challenge for token and returns the number of remaining challenges
+func (l *sharedListener) remove(token string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.challenges, token)
+	return len(l.challenges)
+}
+
+func (l *sharedListener) serve(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, http01.ChallengePath(""))
+
+	l.mutex.RLock()
+	c, found := l.challenges[token]
+	l.mutex.RUnlock()
+
+	if !found || r.Method != http.MethodGet || !c.matches(r) {
+		slog.Warn("acme request: received request which did not match any challenge", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "host", r.Host, "method", r.Method, "path", r.URL.Path)
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	if _, err := w.Write([]byte(c.keyAuth)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.Debug("acme request: served key authorization", "provider", ACME_CHALLENGE_PROVIDER_WEBSERVER, "domain", c.domain)
+}
+
+// getForwardedHost returns the host element of the first proxy in a Forwarded header, as defined in RFC 7239
+func getForwardedHost(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	for _, pair := range strings.Split(first, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && strings.EqualFold(key, "host") {
+			return strings.Trim(value, "\"")
+		}
+	}
+	return ""
+}
@@ -17,29 +17,30 @@
 package config
 
 import (
-	"log/slog"
 	"reflect"
-	"regexp"
 
 	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
 	"github.com/spf13/viper"
 )
 
 type Application struct {
-	ConfigPath string `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
+	ConfigPath       string   `json:"configPath" yaml:"configPath" mapstructure:"configPath"`
+	NameFromFilename bool     `json:"nameFromFilename" yaml:"nameFromFilename" mapstructure:"nameFromFilename"`
+	Include          []string `json:"include" yaml:"include" mapstructure:"include"`
 	// Daemon        Daemon                  `json:"daemon" yaml:"daemon" mapstructure:"daemon"`
 	Organizations []registry.Organization `json:"organizations" yaml:"organizations" mapstructure:"organizations"`
 	Users         []User                  `json:"users" yaml:"users" mapstructure:"users"`
 	Parameters    []ProviderParameters    `json:"providerParameters" yaml:"providerParameters" mapstructure:"providerParameters"`
 }
 
+// UpdateEnvironmentVariables replaces placeholders in the application configuration with their referenced values
 func (a *Application) UpdateEnvironmentVariables(viperEnv *viper.Viper) error {
 	var (
 		err error
 	)
 
 	r := reflect.ValueOf(a)
-	err = reflectValues(r, viperEnv)
+	err = reflectValues(r, newPlaceholderResolver(viperEnv))
 	if err != nil {
 		return err
 	}
@@ -47,7 +48,7 @@ func (a *Application) UpdateEnvironmentVariables(viperEnv *viper.Viper) error {
 	return nil
 }
 
-func reflectValues(r reflect.Value, viperEnv *viper.Viper) error {
+func reflectValues(r reflect.Value, resolver *placeholderResolver) error {
 	var (
 		err error
 		s   reflect.Value
@@ -65,7 +66,7 @@ func reflectValues(r reflect.Value, viperEnv *viper.Viper) error {
 		// Loop over all fields for recursive call
 		for i := 0; i < n; i++ {
 			f := s.Field(i)
-			err = reflectValues(f, viperEnv)
+			err = reflectValues(f, resolver)
 			if err != nil {
 				return err
 			}
@@ -74,13 +75,13 @@ func reflectValues(r reflect.Value, viperEnv *viper.Viper) error {
 		// Loop over all slice elements for recursive call
 		for i := 0; i < s.Len(); i++ {
 			e := s.Index(i)
-			err = reflectValues(e, viperEnv)
+			err = reflectValues(e, resolver)
 			if err != nil {
 				return err
 			}
 		}
 	case reflect.String:
-		err = updateValue(s, viperEnv)
+		err = updateValue(s, resolver)
 		if err != nil {
 			return err
 		}
@@ -88,16 +89,15 @@ func reflectValues(r reflect.Value, viperEnv *viper.Viper) error {
 	return nil
 }
 
-func updateValue(r reflect.Value, viperEnv *viper.Viper) error {
-	re := regexp.MustCompile(`\${LENS_(?P<var>.*)}`)
-	var matches []string
-	if matches = re.FindStringSubmatch(r.String()); matches == nil {
+func updateValue(r reflect.Value, resolver *placeholderResolver) error {
+	if !r.CanSet() || !placeholderRegex.MatchString(r.String()) {
 		return nil
 	}
-	slog.Debug("replacing environment variable", "variable", r.String())
-	v := viperEnv.Get(matches[1])
-	if r.CanSet() {
-		r.Set(reflect.ValueOf(v))
+
+	v, err := resolver.Interpolate(r.String())
+	if err != nil {
+		return err
 	}
+	r.SetString(v)
 	return nil
 }
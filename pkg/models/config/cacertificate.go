@@ -0,0 +1,33 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package config
+
+const (
+	CA_CERTIFICATE_CHECK_MANDATORY = "Mandatory"
+	CA_CERTIFICATE_CHECK_OPTIONAL  = "Optional"
+)
+
+// caCertificate is bound as CA certificate to the endpoints of the installation, e.g. for client certificate authentication
+// Either the issuer of the requested certificate or an existing certkey is bound. When file is set, the certkey is created from
+// the file on NetScaler ADC if it does not exist yet.
+type caCertificate struct {
+	Issuer      bool   `json:"issuer" yaml:"issuer" mapstructure:"issuer"`
+	CertKeyName string `json:"certKeyName" yaml:"certKeyName" mapstructure:"certKeyName"`
+	File        string `json:"file" yaml:"file" mapstructure:"file"`
+	OcspCheck   string `json:"ocspCheck" yaml:"ocspCheck" mapstructure:"ocspCheck"`
+	CrlCheck    string `json:"crlCheck" yaml:"crlCheck" mapstructure:"crlCheck"`
+}
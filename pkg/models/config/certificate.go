@@ -16,7 +16,15 @@
 
 package config
 
-import "github.com/go-acme/lego/v4/certificate"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-acme/lego/v4/certificate"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers"
+)
 
 type Certificate struct {
 	Name         string                `json:"name" yaml:"name" mapstructure:"name"`
@@ -24,3 +32,159 @@ type Certificate struct {
 	Installation []Installation        `json:"installation" yaml:"installation" mapstructure:"installation"`
 	Resource     *certificate.Resource `json:"-" yaml:"-" mapstructure:"-"`
 }
+
+// SplitByKeyType returns a certificate configuration per key type defined in Request.KeyTypes
+// The name of each certificate is suffixed with the key algorithm, e.g. <name>_rsa and <name>_ec
+func (c Certificate) SplitByKeyType() ([]Certificate, error) {
+	var (
+		output   []Certificate
+		suffixes = make(map[string]string)
+	)
+
+	if len(c.Request.KeyTypes) == 0 {
+		return []Certificate{c}, nil
+	}
+
+	if c.Request.KeyType != "" {
+		return nil, fmt.Errorf("keyType and keyTypes cannot be combined for certificate %s", c.Name)
+	}
+
+	for _, keyType := range c.Request.KeyTypes {
+		var suffix string
+		switch {
+		case strings.HasPrefix(keyType, "EC"):
+			suffix = "_ec"
+		case strings.HasPrefix(keyType, "RSA"):
+			suffix = "_rsa"
+		default:
+			return nil, fmt.Errorf("invalid key type %s for certificate %s", keyType, c.Name)
+		}
+
+		// Each certificate needs a unique name, so only one key type per algorithm is allowed
+		if existing, found := suffixes[suffix]; found {
+			return nil, fmt.Errorf("key types %s and %s use the same algorithm for certificate %s", existing, keyType, c.Name)
+		}
+		suffixes[suffix] = keyType
+
+		variant := c
+		variant.Name = c.Name + suffix
+		variant.Request.KeyType = keyType
+		variant.Request.KeyTypes = nil
+
+		// Custom certkey names and filenames must be unique per variant as well, so variants do not overwrite each other's files
+		variant.Installation = make([]Installation, len(c.Installation))
+		for i, installation := range c.Installation {
+			if installation.CertKeyName != "" {
+				installation.CertKeyName = installation.CertKeyName + suffix
+			}
+			if installation.Filename != "" && !strings.Contains(installation.Filename, INSTALLATION_FILENAME_PLACEHOLDER_NAME) {
+				installation.Filename = installation.Filename + suffix
+			}
+			variant.Installation[i] = installation
+		}
+		output = append(output, variant)
+	}
+	return output, nil
+}
+
+// Validate checks the certificate configuration for missing or invalid settings, without connecting to any external system
+func (c Certificate) Validate() error {
+	var errs []error
+
+	if c.Name == "" {
+		errs = append(errs, fmt.Errorf("missing certificate name"))
+	}
+	if c.Request.Target.Organization == "" || c.Request.Target.Environment == "" {
+		errs = append(errs, fmt.Errorf("missing request target for certificate %s", c.Name))
+	}
+	if c.Request.User == "" {
+		errs = append(errs, fmt.Errorf("missing request user for certificate %s", c.Name))
+	}
+	if c.Request.Challenge.Service == "" {
+		errs = append(errs, fmt.Errorf("missing challenge service for certificate %s", c.Name))
+	}
+
+	switch c.Request.Challenge.Type {
+	case ACME_CHALLENGE_TYPE_HTTP, ACME_CHALLENGE_TYPE_DNS:
+		// Providers which are not registered in lens are lego DNS providers, which are validated when the request is executed
+		if p, found := providers.Get(c.Request.Challenge.Provider); found && !p.SupportsChallengeType(c.Request.Challenge.Type) {
+			errs = append(errs, fmt.Errorf("provider %s does not support challenge type %s for certificate %s", c.Request.Challenge.Provider, c.Request.Challenge.Type, c.Name))
+		} else if !found && c.Request.Challenge.Type != ACME_CHALLENGE_TYPE_DNS {
+			errs = append(errs, fmt.Errorf("invalid provider %s for challenge type %s for certificate %s", c.Request.Challenge.Provider, c.Request.Challenge.Type, c.Name))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid challenge type %s for certificate %s", c.Request.Challenge.Type, c.Name))
+	}
+
+	keyTypes := append([]string{}, c.Request.KeyTypes...)
+	if c.Request.KeyType != "" {
+		keyTypes = append(keyTypes, c.Request.KeyType)
+	}
+	for _, keyType := range keyTypes {
+		switch keyType {
+		case ACME_KEY_TYPE_EC256, ACME_KEY_TYPE_EC384, ACME_KEY_TYPE_RSA2048, ACME_KEY_TYPE_RSA4096, ACME_KEY_TYPE_RSA8192:
+		default:
+			errs = append(errs, fmt.Errorf("invalid key type %s for certificate %s", keyType, c.Name))
+		}
+	}
+	if _, err := c.SplitByKeyType(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Request.CsrFile == "" {
+		// Domains are not resolved, so certificates for internal names can be configured on hosts without access to their DNS
+		if domains, err := c.Request.ListDomains(); err != nil {
+			errs = append(errs, err)
+		} else if len(domains) == 0 {
+			errs = append(errs, fmt.Errorf("missing domains for certificate %s", c.Name))
+		}
+	}
+
+	if len(c.Installation) == 0 {
+		errs = append(errs, fmt.Errorf("missing installation for certificate %s", c.Name))
+	}
+	for _, i := range c.Installation {
+		if i.Target.Organization == "" || i.Target.Environment == "" {
+			errs = append(errs, fmt.Errorf("missing installation target for certificate %s", c.Name))
+		}
+		// Certificate files are never overwritten on the target, so every renewal needs a unique filename
+		if i.Filename != "" && !strings.Contains(i.Filename, INSTALLATION_FILENAME_PLACEHOLDER_TIMESTAMP) {
+			errs = append(errs, fmt.Errorf("filename %s must contain %s for certificate %s", i.Filename, INSTALLATION_FILENAME_PLACEHOLDER_TIMESTAMP, c.Name))
+		}
+		if strings.ContainsRune(i.GetFilename(c.Name, ""), '/') {
+			errs = append(errs, fmt.Errorf("filename %s cannot contain a directory for certificate %s", i.Filename, c.Name))
+		}
+		if i.SafeReplace && !i.ReplaceDefaultCertificate {
+			errs = append(errs, fmt.Errorf("safeReplace requires replaceDefaultCertificate for certificate %s", c.Name))
+		}
+		if i.RevertOverHttp && !i.SafeReplace {
+			errs = append(errs, fmt.Errorf("revertOverHttp requires safeReplace for certificate %s", c.Name))
+		}
+		for _, vserver := range i.SslVirtualServers {
+			switch vserver.Type {
+			case "", SSL_VSERVER_TYPE_LB, SSL_VSERVER_TYPE_CS, SSL_VSERVER_TYPE_VPN, SSL_VSERVER_TYPE_AAA, SSL_VSERVER_TYPE_GSLB:
+			default:
+				errs = append(errs, fmt.Errorf("invalid type %s for vserver %s for certificate %s", vserver.Type, vserver.Name, c.Name))
+			}
+			if vserver.Verify.Port < 0 || vserver.Verify.Port > 65535 {
+				errs = append(errs, fmt.Errorf("invalid verification port %d for vserver %s for certificate %s", vserver.Verify.Port, vserver.Name, c.Name))
+			}
+		}
+		for _, ca := range i.CaCertificates {
+			if ca.Issuer == (ca.CertKeyName != "") {
+				errs = append(errs, fmt.Errorf("ca certificate must set either issuer or certKeyName for certificate %s", c.Name))
+			}
+			if ca.File != "" && ca.CertKeyName == "" {
+				errs = append(errs, fmt.Errorf("ca certificate file %s requires certKeyName for certificate %s", ca.File, c.Name))
+			}
+			for _, check := range []string{ca.OcspCheck, ca.CrlCheck} {
+				switch check {
+				case "", CA_CERTIFICATE_CHECK_MANDATORY, CA_CERTIFICATE_CHECK_OPTIONAL:
+				default:
+					errs = append(errs, fmt.Errorf("invalid ca certificate check %s for certificate %s", check, c.Name))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -32,16 +32,11 @@ const (
 	ACME_CHALLENGE_TYPE_TLS_ALPN = "tls-alpn-01"
 )
 
-// Generic webserver provider
-// Other providers constants are defined in their respective codebase
-const (
-	ACME_CHALLENGE_PROVIDER_WEBSERVER = "webserver"
-)
-
 type Challenge struct {
-	Service                    string `json:"service" yaml:"service" mapstructure:"service"`
-	Type                       string `json:"type" yaml:"type" mapstructure:"type"`
-	Provider                   string `json:"provider" yaml:"provider" mapstructure:"provider"`
-	DisableDnsPropagationCheck bool   `json:"disableDnsPropagationCheck" yaml:"disableDnsPropagationCheck" mapstructure:"disableDnsPropagationCheck"`
-	ProviderParameters         string `json:"providerParameters" yaml:"providerParameters" mapstructure:"providerParameters"`
+	Service                    string                 `json:"service" yaml:"service" mapstructure:"service"`
+	Type                       string                 `json:"type" yaml:"type" mapstructure:"type"`
+	Provider                   string                 `json:"provider" yaml:"provider" mapstructure:"provider"`
+	DisableDnsPropagationCheck bool                   `json:"disableDnsPropagationCheck" yaml:"disableDnsPropagationCheck" mapstructure:"disableDnsPropagationCheck"`
+	ProviderParameters         string                 `json:"providerParameters" yaml:"providerParameters" mapstructure:"providerParameters"`
+	ProviderOptions            map[string]interface{} `json:"providerOptions" yaml:"providerOptions" mapstructure:"providerOptions"`
 }
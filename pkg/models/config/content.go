@@ -31,7 +31,22 @@ type Content struct {
 	SubjectAlternativeNamesFile string   `json:"subjectAlternativeNamesFile" yaml:"subjectAlternativeNamesFile" mapstructure:"subjectAlternativeNamesFile"`
 }
 
+// GetDomains returns the domains of the certificate and checks that every domain, except wildcard domains, resolves in DNS
 func (c Content) GetDomains(basePath string) ([]string, error) {
+	var (
+		err    error
+		output []string
+	)
+
+	output, err = c.ListDomains(basePath)
+	if err != nil {
+		return output, err
+	}
+	return output, c.validateDomains(output)
+}
+
+// ListDomains returns the domains of the certificate without checking them in DNS
+func (c Content) ListDomains(basePath string) ([]string, error) {
 	var (
 		err     error
 		output  []string
@@ -49,7 +64,7 @@ func (c Content) GetDomains(basePath string) ([]string, error) {
 	}
 
 	output = append(output, domains...)
-	return output, c.validateDomains(output)
+	return output, nil
 }
 
 func (c Content) GetDomainsFromFile(basePath string) ([]string, error) {
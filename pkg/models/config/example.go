@@ -0,0 +1,307 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/netscaleradc"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/webserver"
+)
+
+var (
+	certificateExampleComments = map[string]string{
+		"":                           "Certificate configuration, stored as a separate file in the configPath of the global configuration",
+		"name":                       "Unique name of the certificate, the certificate is installed as LENS_<name> unless installation.certKeyName is set",
+		"request":                    "Settings for the certificate request to the ACME service",
+		"request.target":             "Environment used to execute the challenge, defined in the organizations section of the global configuration",
+		"request.target.partition":   "Admin partition in which the challenge is executed, leave empty for the default partition",
+		"request.user":               "User from the users section of the global configuration",
+		"request.challenge.service":  "LE_STAGING | LE_PRODUCTION | <directory url of the ACME service>",
+		"request.challenge.type":     "http-01 | dns-01",
+		"request.challenge.provider": "netscaler-http-global (http-01) | netscaler-adns (dns-01) | webserver (http-01) | <name of lego dns provider> (dns-01)",
+		"request.challenge.disableDnsPropagationCheck": "Do not wait for DNS propagation for dns-01 challenges, e.g. when using split-DNS",
+		"request.challenge.providerParameters":         "Name of the providerParameters from the global configuration, used by lego dns providers",
+		"request.keyType":                              "RSA2048 | RSA4096 | RSA8192 | EC256 | EC384",
+		"request.keyTypes":                             "Request a certificate per key type instead of keyType, with at most one RSA and one EC key type\nkeyTypes:\n  - RSA4096\n  - EC256",
+		"request.preferredChain":                       "Common name of the issuer at the top of the preferred chain, e.g. ISRG Root X1",
+		"request.reuseKey":                             "Reuse the private key of the installed certificate",
+		"request.csrFile":                              "Certificate signing request used instead of content, relative to the configPath",
+		"request.csrKeyFile":                           "Path of the private key for csrFile on NetScaler ADC",
+		"request.rootsFile":                            "PEM file with trusted root certificates, relative to the configPath, defaults to the system roots, LE_STAGING only checks the returned chain without rootsFile",
+		"request.content.commonName":                   "Common name of the certificate",
+		"request.content.subjectAlternativeNames":      "Subject alternative names of the certificate",
+		"request.content.subjectAlternativeNamesFile":  "File with a subject alternative name per line, relative to the configPath",
+		"installation":                                 "Environments on which the certificate is installed",
+		"installation.target.partition":                "Admin partition in which the certificate is installed, leave empty for the default partition",
+		"installation.certKeyName":                     "Name of the certkey on NetScaler ADC, defaults to LENS_<name>",
+		"installation.directory":                       "Directory for the certificate files on NetScaler ADC, defaults to /nsconfig/ssl/LENS/",
+		"installation.filename":                        "Filename of the certificate files without extension, supports {name} and {timestamp}, defaults to {name}_{timestamp}",
+		"installation.replaceDefaultCertificate":       "Replace ns-server-certificate, used for the management interface of NetScaler ADC",
+		"installation.safeReplace":                     "Verify the key pair and revert to the previous default certificate when NITRO over HTTPS fails afterwards",
+		"installation.revertOverHttp":                  "Revert the default certificate over plain HTTP instead of the connection settings of the environment, requires safeReplace",
+		"installation.pruneBindings":                   "Unbind the certificate from ssl vservers, ssl services, ssl service groups and ssl profiles which are not defined below\nOnly endpoint types with at least one endpoint below are pruned",
+		"installation.sslVirtualServers":               "SSL virtual servers to bind the certificate to",
+		"installation.sslVirtualServers.type":          "lb | cs | vpn | aaa | gslb, optional, verifies the type of the virtual server before binding",
+		"installation.sslVirtualServers.verify":        "Optional TLS handshake with address:port for every domain after installation, disabled when address is empty, port defaults to 443\nverify:\n  address: 192.168.1.100\n  port: 443",
+		"installation.sslServices":                     "SSL services to bind the certificate to",
+		"installation.sslServiceGroups":                "SSL service groups to bind the certificate to",
+		"installation.sslProfiles":                     "SSL profiles to bind the certificate to",
+		"installation.caCertificates":                  "CA certificates to bind to the ssl vservers, ssl services, ssl service groups and ssl profiles, e.g. for client certificate authentication\ncaCertificates:\n  - issuer: true        # bind the issuer of the certificate, instead of certKeyName\n    certKeyName: \"\"     # name of an existing CA certkey, or of the certkey to create from file\n    file: \"\"            # path of the CA certificate file on NetScaler ADC\n    ocspCheck: \"\"       # Mandatory | Optional\n    crlCheck: Optional  # Mandatory | Optional",
+	}
+
+	applicationExampleComments = map[string]string{
+		"":                                      "Global configuration for lens",
+		"configPath":                            "Path to the certificate configuration files",
+		"nameFromFilename":                      "Use the filename as the name of certificate configurations without a name",
+		"include":                               "Globs matching files with organizations, users and providerParameters, relative to this file",
+		"organizations":                         "NetScaler ADC environments, referenced as target in certificate configurations",
+		"organizations.environments.type":       "standalone | hapair | cluster",
+		"organizations.environments.management": "Management address (SNIP) of the environment, leave empty to connect to the nodes (NSIP) directly",
+		"organizations.environments.credentials.password": "Placeholders are replaced from other sources: ${LENS_<name>} | ${file:<path>} | ${credential:<name>} | ${vault:<path>#<key>}",
+		"users":              "ACME accounts, referenced as user in certificate configurations",
+		"users.eab":          "External account binding, required by some ACME services",
+		"providerParameters": "Environment variables for lego dns providers, referenced as providerParameters in certificate configurations",
+	}
+)
+
+// GetCertificateExample returns a commented YAML example of the certificate configuration
+func GetCertificateExample() ([]byte, error) {
+	comments := make(map[string]string, len(certificateExampleComments)+1)
+	for k, v := range certificateExampleComments {
+		comments[k] = v
+	}
+	comments["request.challenge.providerOptions"] = strings.Join([]string{
+		"Options of the challenge provider, with their default value",
+		describeProviderOptions(netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, netscaleradc.NewProviderOptions()),
+		describeProviderOptions(netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, netscaleradc.NewProviderOptions()),
+		describeProviderOptions(webserver.ACME_CHALLENGE_PROVIDER_WEBSERVER, webserver.NewProviderOptions()),
+	}, "\n")
+	return marshalExample(newCertificateExample(), comments)
+}
+
+// GetApplicationExample returns a commented YAML example of the global configuration
+func GetApplicationExample() ([]byte, error) {
+	var (
+		err    error
+		output Application
+	)
+	if output, err = newApplicationExample(); err != nil {
+		return nil, err
+	}
+	return marshalExample(output, applicationExampleComments)
+}
+
+func newCertificateExample() Certificate {
+	return Certificate{
+		Name: "corelogic",
+		Request: Request{
+			Target: Target{
+				Organization: "corelayer",
+				Environment:  "development",
+				Partition:    "",
+			},
+			User: "corelayer_acme",
+			Challenge: Challenge{
+				Service:                    ACME_SERVICE_LETSENCRYPT_STAGING,
+				Type:                       ACME_CHALLENGE_TYPE_HTTP,
+				Provider:                   netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL,
+				DisableDnsPropagationCheck: false,
+				ProviderParameters:         "",
+				ProviderOptions: map[string]interface{}{
+					"maxRetries": netscaleradc.NewProviderOptions().MaxRetries,
+				},
+			},
+			KeyType:        ACME_KEY_TYPE_RSA4096,
+			KeyTypes:       []string{},
+			PreferredChain: "",
+			ReuseKey:       false,
+			CsrFile:        "",
+			CsrKeyFile:     "",
+			Content: Content{
+				CommonName:                  "corelogic.dev.corelayer.eu",
+				SubjectAlternativeNames:     []string{"www.corelogic.dev.corelayer.eu"},
+				SubjectAlternativeNamesFile: "",
+			},
+			RootsFile: "",
+		},
+		Installation: []Installation{
+			{
+				Target: Target{
+					Organization: "corelayer",
+					Environment:  "development",
+					Partition:    "",
+				},
+				CertKeyName:               "",
+				Directory:                 "",
+				Filename:                  "",
+				ReplaceDefaultCertificate: false,
+				SafeReplace:               false,
+				RevertOverHttp:            false,
+				PruneBindings:             false,
+				SslVirtualServers: []sslVirtualServer{
+					{Name: "CSV_DEV_SSL", Type: SSL_VSERVER_TYPE_CS, SniEnabled: true},
+				},
+				SslServices: []sslService{
+					{Name: "SVC_DEV_SSL", SniEnabled: false},
+				},
+				SslServiceGroups: []sslServiceGroup{
+					{Name: "SVCG_DEV_SSL", SniEnabled: false},
+				},
+				SslProfiles: []sslProfile{
+					{Name: "SSLP_DEV", SniEnabled: true},
+				},
+				CaCertificates: []caCertificate{},
+			},
+		},
+	}
+}
+
+// newApplicationExample decodes the organizations into the registry types, so the example contains every field of the environment
+func newApplicationExample() (Application, error) {
+	var (
+		err           error
+		organizations []registry.Organization
+	)
+
+	err = mapstructure.Decode([]map[string]interface{}{
+		{
+			"name": "corelayer",
+			"environments": []map[string]interface{}{
+				{
+					"name": "development",
+					"type": "hapair",
+					"management": map[string]interface{}{
+						"name":    "vpx-dev",
+						"address": "192.168.1.10",
+					},
+					"nodes": []map[string]interface{}{
+						{"name": "vpx-dev-001", "address": "192.168.1.11"},
+						{"name": "vpx-dev-002", "address": "192.168.1.12"},
+					},
+					"credentials": map[string]interface{}{
+						"username": "nsroot",
+						"password": "${LENS_DEV_PASS}",
+					},
+					"connectionSettings": map[string]interface{}{
+						"useSsl":                    true,
+						"timeout":                   3000,
+						"validateServerCertificate": true,
+						"logTlsSecrets":             false,
+						"autoLogin":                 false,
+					},
+				},
+			},
+		},
+	}, &organizations)
+	if err != nil {
+		return Application{}, fmt.Errorf("could not create example organizations with message %w", err)
+	}
+
+	return Application{
+		ConfigPath:       "conf.d",
+		NameFromFilename: false,
+		Include:          []string{"organizations.d/*.yaml"},
+		Organizations:    organizations,
+		Users: []User{
+			{
+				Name:  "corelayer_acme",
+				Email: "acme@corelayer.eu",
+				ExternalAccountBinding: ExternalAccountBinding{
+					Kid:         "",
+					HmacEncoded: "",
+				},
+			},
+		},
+		Parameters: []ProviderParameters{
+			{
+				Name: "cloudflare",
+				Variables: []EnvironmentVariable{
+					{Name: "CLOUDFLARE_DNS_API_TOKEN", Value: "${file:/run/secrets/cloudflare}"},
+				},
+			},
+		},
+	}, nil
+}
+
+func marshalExample(v interface{}, comments map[string]string) ([]byte, error) {
+	var (
+		err    error
+		node   yaml.Node
+		output bytes.Buffer
+	)
+
+	if err = node.Encode(v); err != nil {
+		return nil, fmt.Errorf("could not encode example with message %w", err)
+	}
+	addExampleComments(&node, "", comments)
+	if len(node.Content) > 0 {
+		node.Content[0].HeadComment = strings.TrimSpace(comments[""] + "\n\n" + node.Content[0].HeadComment)
+	}
+
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("could not encode example with message %w", err)
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, fmt.Errorf("could not encode example with message %w", err)
+	}
+	return output.Bytes(), nil
+}
+
+// addExampleComments adds the comment for each key path, e.g. request.challenge.type, to the keys in node
+// Only the first element of a sequence is commented, to avoid repeating the same comment for every element
+func addExampleComments(node *yaml.Node, path string, comments map[string]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			keyPath := key.Value
+			if path != "" {
+				keyPath = path + "." + key.Value
+			}
+			if comment, found := comments[keyPath]; found {
+				key.HeadComment = comment
+			}
+			addExampleComments(node.Content[i+1], keyPath, comments)
+		}
+	case yaml.SequenceNode:
+		if len(node.Content) > 0 {
+			addExampleComments(node.Content[0], path, comments)
+		}
+	}
+}
+
+// describeProviderOptions lists the options of a challenge provider with their default value
+func describeProviderOptions(provider string, options interface{}) string {
+	var output []string
+
+	v := reflect.ValueOf(options)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("mapstructure")
+		output = append(output, fmt.Sprintf("%s=%#v", name, v.Field(i).Interface()))
+	}
+	return "  " + provider + ": " + strings.Join(output, ", ")
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"github.com/spf13/viper"
+)
+
+// applicationInclude holds the sections of the application configuration which can be defined in an included file
+type applicationInclude struct {
+	Include       []string                `json:"include" yaml:"include" mapstructure:"include"`
+	Organizations []registry.Organization `json:"organizations" yaml:"organizations" mapstructure:"organizations"`
+	Users         []User                  `json:"users" yaml:"users" mapstructure:"users"`
+	Parameters    []ProviderParameters    `json:"providerParameters" yaml:"providerParameters" mapstructure:"providerParameters"`
+}
+
+// includeTracker keeps the file in which each organization, user and providerParameters entry is defined to report conflicts
+type includeTracker struct {
+	organizations map[string]string
+	users         map[string]string
+	parameters    map[string]string
+	errs          []error
+}
+
+func (t *includeTracker) track(kind string, locations map[string]string, name string, file string) {
+	if location, found := locations[name]; found {
+		slog.Error("duplicate entry in configuration", "type", kind, "name", name, "file", file, "existing", location)
+		t.errs = append(t.errs, fmt.Errorf("duplicate %s %s in %s, already defined in %s", kind, name, file, location))
+		return
+	}
+	locations[name] = file
+}
+
+func (t *includeTracker) add(a applicationInclude, file string) {
+	for _, o := range a.Organizations {
+		t.track("organization", t.organizations, o.Name, file)
+	}
+	for _, u := range a.Users {
+		t.track("user", t.users, u.Name, file)
+	}
+	for _, p := range a.Parameters {
+		t.track("providerParameters", t.parameters, p.Name, file)
+	}
+}
+
+// MergeIncludes merges organizations, users and providerParameters from the files matching the include globs
+// Relative globs are resolved against the directory of configFile, which is the file the application configuration was read from
+// Entries with the same name in different files, or in the same file, are reported as a conflict
+func (a *Application) MergeIncludes(configFile string) error {
+	var (
+		err   error
+		files []string
+	)
+
+	t := &includeTracker{
+		organizations: make(map[string]string),
+		users:         make(map[string]string),
+		parameters:    make(map[string]string),
+	}
+	t.add(applicationInclude{Organizations: a.Organizations, Users: a.Users, Parameters: a.Parameters}, configFile)
+
+	basePath := filepath.Dir(configFile)
+	for _, pattern := range a.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(basePath, pattern)
+		}
+
+		if files, err = filepath.Glob(pattern); err != nil {
+			slog.Error("invalid include pattern", "pattern", pattern, "error", err)
+			return fmt.Errorf("invalid include pattern %s with message %w", pattern, err)
+		}
+		if len(files) == 0 {
+			slog.Warn("include pattern does not match any file", "pattern", pattern)
+		}
+
+		for _, file := range files {
+			var include applicationInclude
+			slog.Debug("including configuration", "file", file)
+			if include, err = a.readInclude(file); err != nil {
+				return err
+			}
+
+			t.add(include, file)
+			a.Organizations = append(a.Organizations, include.Organizations...)
+			a.Users = append(a.Users, include.Users...)
+			a.Parameters = append(a.Parameters, include.Parameters...)
+		}
+	}
+	return errors.Join(t.errs...)
+}
+
+func (a *Application) readInclude(file string) (applicationInclude, error) {
+	var (
+		err    error
+		output applicationInclude
+	)
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err = v.ReadInConfig(); err != nil {
+		slog.Error("could not read included configuration", "file", file, "error", err)
+		return output, fmt.Errorf("could not read included configuration %s with message %w", file, err)
+	}
+
+	if err = v.Unmarshal(&output); err != nil {
+		slog.Error("could not unmarshal included configuration", "file", file, "error", err)
+		return output, fmt.Errorf("could not unmarshal included configuration %s with message %w", file, err)
+	}
+
+	// Only the application configuration file can include other files, to keep the origin of every entry obvious
+	if len(output.Include) > 0 {
+		slog.Error("nested include is not supported", "file", file)
+		return output, fmt.Errorf("nested include in %s is not supported", file)
+	}
+	return output, nil
+}
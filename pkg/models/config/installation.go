@@ -16,9 +16,68 @@
 
 package config
 
+import (
+	"strings"
+)
+
+const (
+	INSTALLATION_FILENAME_PLACEHOLDER_NAME      = "{name}"
+	INSTALLATION_FILENAME_PLACEHOLDER_TIMESTAMP = "{timestamp}"
+	INSTALLATION_FILENAME_DEFAULT               = INSTALLATION_FILENAME_PLACEHOLDER_NAME + "_" + INSTALLATION_FILENAME_PLACEHOLDER_TIMESTAMP
+)
+
 type Installation struct {
 	Target                    Target             `json:"target" yaml:"target" mapstructure:"target"`
+	CertKeyName               string             `json:"certKeyName" yaml:"certKeyName" mapstructure:"certKeyName"`
+	Directory                 string             `json:"directory" yaml:"directory" mapstructure:"directory"`
+	Filename                  string             `json:"filename" yaml:"filename" mapstructure:"filename"`
 	ReplaceDefaultCertificate bool               `json:"replaceDefaultCertificate" yaml:"replaceDefaultCertificate" mapstructure:"replaceDefaultCertificate"`
+	SafeReplace               bool               `json:"safeReplace" yaml:"safeReplace" mapstructure:"safeReplace"`
+	RevertOverHttp            bool               `json:"revertOverHttp" yaml:"revertOverHttp" mapstructure:"revertOverHttp"`
+	PruneBindings             bool               `json:"pruneBindings" yaml:"pruneBindings" mapstructure:"pruneBindings"`
 	SslVirtualServers         []sslVirtualServer `json:"sslVirtualServers" yaml:"sslVirtualServers" mapstructure:"sslVirtualServers"`
 	SslServices               []sslService       `json:"sslServices" yaml:"sslServices" mapstructure:"sslServices"`
+	SslServiceGroups          []sslServiceGroup  `json:"sslServiceGroups" yaml:"sslServiceGroups" mapstructure:"sslServiceGroups"`
+	SslProfiles               []sslProfile       `json:"sslProfiles" yaml:"sslProfiles" mapstructure:"sslProfiles"`
+	CaCertificates            []caCertificate    `json:"caCertificates" yaml:"caCertificates" mapstructure:"caCertificates"`
+}
+
+// GetFilename returns the filename for the certificate files on the target, without extension
+// The filename template supports the {name} and {timestamp} placeholders and defaults to {name}_{timestamp}
+func (i Installation) GetFilename(name string, timestamp string) string {
+	filename := i.Filename
+	if filename == "" {
+		filename = INSTALLATION_FILENAME_DEFAULT
+	}
+	return strings.NewReplacer(INSTALLATION_FILENAME_PLACEHOLDER_NAME, name, INSTALLATION_FILENAME_PLACEHOLDER_TIMESTAMP, timestamp).Replace(filename)
+}
+
+// AddSslVirtualServer adds an SSL virtual server to bind the certificate to
+func (i *Installation) AddSslVirtualServer(name string, sniEnabled bool) {
+	i.SslVirtualServers = append(i.SslVirtualServers, sslVirtualServer{Name: name, SniEnabled: sniEnabled})
+}
+
+// AddSslService adds an SSL service to bind the certificate to
+func (i *Installation) AddSslService(name string, sniEnabled bool) {
+	i.SslServices = append(i.SslServices, sslService{Name: name, SniEnabled: sniEnabled})
+}
+
+// AddSslServiceGroup adds an SSL service group to bind the certificate to
+func (i *Installation) AddSslServiceGroup(name string, sniEnabled bool) {
+	i.SslServiceGroups = append(i.SslServiceGroups, sslServiceGroup{Name: name, SniEnabled: sniEnabled})
+}
+
+// AddSslProfile adds an SSL profile to bind the certificate to
+func (i *Installation) AddSslProfile(name string, sniEnabled bool) {
+	i.SslProfiles = append(i.SslProfiles, sslProfile{Name: name, SniEnabled: sniEnabled})
+}
+
+// AddCaCertificate adds an existing certkey to bind as CA certificate to the endpoints of the installation, if it was not added yet
+func (i *Installation) AddCaCertificate(certKeyName string, ocspCheck string, crlCheck string) {
+	for _, ca := range i.CaCertificates {
+		if ca.CertKeyName == certKeyName {
+			return
+		}
+	}
+	i.CaCertificates = append(i.CaCertificates, caCertificate{CertKeyName: certKeyName, OcspCheck: ocspCheck, CrlCheck: crlCheck})
 }
@@ -0,0 +1,206 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	PLACEHOLDER_PREFIX_ENVIRONMENT = "LENS_"
+	PLACEHOLDER_PREFIX_FILE        = "file:"
+	PLACEHOLDER_PREFIX_VAULT       = "vault:"
+	PLACEHOLDER_PREFIX_CREDENTIAL  = "credential:"
+)
+
+var (
+	placeholderRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+)
+
+// placeholderResolver replaces placeholders in configuration values with their value from the source referenced in the placeholder
+//
+//	${LENS_<name>} is replaced with the environment variable from the environment variables file or the process environment
+//	${file:<path>} is replaced with the contents of the file
+//	${vault:<path>#<key>} is replaced with the key of the secret at path in a HashiCorp Vault compatible KV secrets engine
+//	${credential:<name>} is replaced with the systemd credential passed to the lens service
+type placeholderResolver struct {
+	viperEnv *viper.Viper
+	client   *http.Client
+	secrets  map[string]map[string]interface{}
+}
+
+func newPlaceholderResolver(viperEnv *viper.Viper) *placeholderResolver {
+	return &placeholderResolver{
+		viperEnv: viperEnv,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		secrets:  make(map[string]map[string]interface{}),
+	}
+}
+
+// Interpolate replaces all placeholders in value, including placeholders embedded in a larger string
+// Placeholders which do not match a known source are left untouched
+func (p *placeholderResolver) Interpolate(value string) (string, error) {
+	var errs []error
+
+	output := placeholderRegex.ReplaceAllStringFunc(value, func(placeholder string) string {
+		v, err := p.resolve(placeholder[2 : len(placeholder)-1])
+		if err != nil {
+			errs = append(errs, err)
+			return placeholder
+		}
+		return v
+	})
+	return output, errors.Join(errs...)
+}
+
+func (p *placeholderResolver) resolve(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, PLACEHOLDER_PREFIX_ENVIRONMENT):
+		return p.resolveEnvironmentVariable(strings.TrimPrefix(reference, PLACEHOLDER_PREFIX_ENVIRONMENT))
+	case strings.HasPrefix(reference, PLACEHOLDER_PREFIX_FILE):
+		return p.resolveFile(strings.TrimPrefix(reference, PLACEHOLDER_PREFIX_FILE))
+	case strings.HasPrefix(reference, PLACEHOLDER_PREFIX_VAULT):
+		return p.resolveVaultSecret(strings.TrimPrefix(reference, PLACEHOLDER_PREFIX_VAULT))
+	case strings.HasPrefix(reference, PLACEHOLDER_PREFIX_CREDENTIAL):
+		return p.resolveCredential(strings.TrimPrefix(reference, PLACEHOLDER_PREFIX_CREDENTIAL))
+	default:
+		return "${" + reference + "}", nil
+	}
+}
+
+func (p *placeholderResolver) resolveEnvironmentVariable(name string) (string, error) {
+	slog.Debug("replacing environment variable", "variable", PLACEHOLDER_PREFIX_ENVIRONMENT+name)
+	if !p.viperEnv.IsSet(name) {
+		return "", fmt.Errorf("environment variable %s%s is not defined", PLACEHOLDER_PREFIX_ENVIRONMENT, name)
+	}
+	return p.viperEnv.GetString(name), nil
+}
+
+func (p *placeholderResolver) resolveFile(path string) (string, error) {
+	var (
+		err  error
+		data []byte
+	)
+	slog.Debug("replacing file reference", "file", path)
+	if data, err = os.ReadFile(path); err != nil {
+		return "", fmt.Errorf("could not read secret from file %s with message %w", path, err)
+	}
+	// Files created by editors or secret managers usually end with a newline, which is not part of the secret
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveCredential reads a systemd credential from the directory systemd passes to the service in CREDENTIALS_DIRECTORY
+func (p *placeholderResolver) resolveCredential(name string) (string, error) {
+	slog.Debug("replacing systemd credential", "credential", name)
+	directory := os.Getenv("CREDENTIALS_DIRECTORY")
+	if directory == "" {
+		return "", fmt.Errorf("could not read systemd credential %s: CREDENTIALS_DIRECTORY is not set", name)
+	}
+	if name == "" || strings.ContainsRune(name, filepath.Separator) {
+		return "", fmt.Errorf("could not read systemd credential %s: invalid credential name", name)
+	}
+	return p.resolveFile(filepath.Join(directory, name))
+}
+
+// resolveVaultSecret reads a key from a secret in a HashiCorp Vault compatible KV secrets engine
+// The server and token are read from the VAULT_ADDR and VAULT_TOKEN environment variables, VAULT_NAMESPACE is optional
+//
+//	reference is <path>#<key>, where path is the API path of the secret, e.g. secret/data/netscaler for KV version 2
+func (p *placeholderResolver) resolveVaultSecret(reference string) (string, error) {
+	var (
+		err    error
+		found  bool
+		secret map[string]interface{}
+		value  interface{}
+	)
+	slog.Debug("replacing vault secret", "secret", reference)
+
+	path, key, found := strings.Cut(reference, "#")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference %s, expected <path>#<key>", reference)
+	}
+
+	if secret, found = p.secrets[path]; !found {
+		if secret, err = p.getVaultSecret(path); err != nil {
+			return "", err
+		}
+		p.secrets[path] = secret
+	}
+
+	if value, found = secret[key]; !found {
+		return "", fmt.Errorf("could not find key %s in vault secret %s", key, path)
+	}
+	return fmt.Sprint(value), nil
+}
+
+func (p *placeholderResolver) getVaultSecret(path string) (map[string]interface{}, error) {
+	var (
+		err      error
+		request  *http.Request
+		response *http.Response
+		body     struct {
+			Data   map[string]interface{} `json:"data"`
+			Errors []string               `json:"errors"`
+		}
+	)
+
+	address := os.Getenv("VAULT_ADDR")
+	token := os.Getenv("VAULT_TOKEN")
+	if address == "" || token == "" {
+		return nil, fmt.Errorf("could not read vault secret %s: VAULT_ADDR and VAULT_TOKEN must be set", path)
+	}
+
+	url := strings.TrimRight(address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	if request, err = http.NewRequest(http.MethodGet, url, nil); err != nil {
+		return nil, fmt.Errorf("could not read vault secret %s with message %w", path, err)
+	}
+	request.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		request.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	if response, err = p.client.Do(request); err != nil {
+		return nil, fmt.Errorf("could not read vault secret %s with message %w", path, err)
+	}
+	defer response.Body.Close()
+
+	if err = json.NewDecoder(response.Body).Decode(&body); err != nil && response.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("could not decode vault secret %s with message %w", path, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not read vault secret %s: %s %s", path, response.Status, strings.Join(body.Errors, ", "))
+	}
+
+	// KV version 2 wraps the secret with its metadata
+	if data, ok := body.Data["data"].(map[string]interface{}); ok {
+		if _, ok = body.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return body.Data, nil
+}
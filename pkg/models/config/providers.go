@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 CoreLayer BV
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package config
+
+import (
+	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/http01"
+
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/netscaleradc"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/webserver"
+)
+
+// Register the challenge providers included in lens
+// Environment "env" is a special environment, in which the connection to NetScaler ADC is configured using environment variables
+func init() {
+	providers.Register(netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL, []string{ACME_CHALLENGE_TYPE_HTTP}, netscaleradc.NewProviderOptions(),
+		func(environment registry.Environment, partition string, timestamp string, options netscaleradc.ProviderOptions) (challenge.Provider, error) {
+			if environment.Name == "env" {
+				return netscaleradc.NewGlobalHttpProviderFromEnv(partition, options.MaxRetries, timestamp)
+			}
+			return netscaleradc.NewGlobalHttpProvider(environment, partition, options.MaxRetries, timestamp)
+		})
+
+	providers.Register(netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS, []string{ACME_CHALLENGE_TYPE_DNS}, netscaleradc.NewProviderOptions(),
+		func(environment registry.Environment, partition string, timestamp string, options netscaleradc.ProviderOptions) (challenge.Provider, error) {
+			if environment.Name == "env" {
+				return netscaleradc.NewADnsProviderFromEnv(partition, options.MaxRetries)
+			}
+			return netscaleradc.NewADnsProvider(environment, partition, options.MaxRetries)
+		})
+
+	providers.Register(webserver.ACME_CHALLENGE_PROVIDER_WEBSERVER, []string{ACME_CHALLENGE_TYPE_HTTP}, webserver.NewProviderOptions(),
+		func(environment registry.Environment, partition string, timestamp string, options webserver.ProviderOptions) (challenge.Provider, error) {
+			var (
+				err      error
+				upstream challenge.Provider
+				p        *netscaleradc.GlobalHttpProvider
+			)
+
+			if options.Shared {
+				upstream = webserver.NewSharedHttpProvider(options.Address, options.GetPort(), options.HostHeader)
+			} else {
+				s := http01.NewProviderServer(options.Address, options.GetPort())
+				s.SetProxyHeader(options.HostHeader)
+				upstream = s
+			}
+
+			if !options.Redirect {
+				return upstream, nil
+			}
+
+			if environment.Name == "env" {
+				p, err = netscaleradc.NewGlobalHttpProviderFromEnv(partition, options.MaxRetries, timestamp)
+			} else {
+				p, err = netscaleradc.NewGlobalHttpProvider(environment, partition, options.MaxRetries, timestamp)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return netscaleradc.NewRedirectHttpProvider(p, upstream, options.RedirectAddress, options.GetPort())
+		})
+}
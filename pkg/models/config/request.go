@@ -17,13 +17,18 @@
 package config
 
 import (
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
 	"github.com/corelayer/netscaleradc-nitro-go/pkg/registry"
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/challenge"
-	"github.com/go-acme/lego/v4/challenge/http01"
 	"github.com/go-acme/lego/v4/providers/dns"
 
-	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers/netscaleradc"
+	"github.com/corelayer/netscaleradc-acme-go/pkg/lego/providers"
 )
 
 const (
@@ -35,12 +40,18 @@ const (
 )
 
 type Request struct {
-	Target    Target    `json:"target" yaml:"target" mapstructure:"target"`
-	User      string    `json:"user" yaml:"user" mapstructure:"user"`
-	Challenge Challenge `json:"challenge" yaml:"challenge" mapstructure:"challenge"`
-	KeyType   string    `json:"keyType" yaml:"keyType" mapstructure:"keyType"`
-	Content   Content   `json:"content" yaml:"content" mapstructure:"content"`
-	basePath  string
+	Target         Target    `json:"target" yaml:"target" mapstructure:"target"`
+	User           string    `json:"user" yaml:"user" mapstructure:"user"`
+	Challenge      Challenge `json:"challenge" yaml:"challenge" mapstructure:"challenge"`
+	KeyType        string    `json:"keyType" yaml:"keyType" mapstructure:"keyType"`
+	KeyTypes       []string  `json:"keyTypes" yaml:"keyTypes" mapstructure:"keyTypes"`
+	PreferredChain string    `json:"preferredChain" yaml:"preferredChain" mapstructure:"preferredChain"`
+	ReuseKey       bool      `json:"reuseKey" yaml:"reuseKey" mapstructure:"reuseKey"`
+	CsrFile        string    `json:"csrFile" yaml:"csrFile" mapstructure:"csrFile"`
+	CsrKeyFile     string    `json:"csrKeyFile" yaml:"csrKeyFile" mapstructure:"csrKeyFile"`
+	Content        Content   `json:"content" yaml:"content" mapstructure:"content"`
+	RootsFile      string    `json:"rootsFile" yaml:"rootsFile" mapstructure:"rootsFile"`
+	basePath       string
 }
 
 func (r Request) GetServiceUrl() string {
@@ -55,28 +66,89 @@ func (r Request) GetServiceUrl() string {
 }
 
 func (r Request) GetChallengeProvider(environment registry.Environment, timestamp string) (challenge.Provider, error) {
-	switch r.Challenge.Provider {
-	case netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_HTTP_GLOBAL:
-		if environment.Name == "env" {
-			return netscaleradc.NewGlobalHttpProviderFromEnv(10, timestamp)
+	p, found := providers.Get(r.Challenge.Provider)
+	if !found {
+		// Fall back to the DNS providers included in lego
+		if r.Challenge.Type != ACME_CHALLENGE_TYPE_DNS {
+			return nil, fmt.Errorf("unknown challenge provider %s for challenge type %s", r.Challenge.Provider, r.Challenge.Type)
 		}
-		return netscaleradc.NewGlobalHttpProvider(environment, 10, timestamp)
-	case netscaleradc.ACME_CHALLENGE_PROVIDER_NETSCALER_ADNS:
-		if environment.Name == "env" {
-			return netscaleradc.NewADnsProviderFromEnv(10)
+		return dns.NewDNSChallengeProviderByName(r.Challenge.Provider)
+	}
+
+	if !p.SupportsChallengeType(r.Challenge.Type) {
+		return nil, fmt.Errorf("challenge provider %s does not support challenge type %s", r.Challenge.Provider, r.Challenge.Type)
+	}
+	return p.NewChallengeProvider(environment, r.Target.Partition, timestamp, r.Challenge.ProviderOptions)
+}
+
+// GetCsr reads the certificate signing request from CsrFile
+// Relative paths are resolved against the directory of the certificate configuration files
+func (r Request) GetCsr() (*x509.CertificateRequest, error) {
+	var (
+		err      error
+		filename string
+		content  []byte
+	)
+
+	filename = r.CsrFile
+	if _, err = os.Stat(filename); err != nil {
+		filename = filepath.Join(r.basePath, r.CsrFile)
+		if _, err = os.Stat(filename); err != nil {
+			slog.Error("could not read csr from file", "filename", filename, "error", err)
+			return nil, err
+		}
+	}
+
+	if content, err = os.ReadFile(filename); err != nil {
+		slog.Error("could not read csr from file", "filename", filename, "error", err)
+		return nil, err
+	}
+
+	return certcrypto.PemDecodeTox509CSR(content)
+}
+
+// GetRootCertificates returns the root certificates to verify the issued certificate chain against
+// The system root certificates are used when no roots file is configured
+func (r Request) GetRootCertificates() (*x509.CertPool, error) {
+	var (
+		err      error
+		filename string
+		content  []byte
+		output   = x509.NewCertPool()
+	)
+
+	if r.RootsFile == "" {
+		return x509.SystemCertPool()
+	}
+
+	filename = r.RootsFile
+	if _, err = os.Stat(filename); err != nil {
+		filename = filepath.Join(r.basePath, r.RootsFile)
+		if _, err = os.Stat(filename); err != nil {
+			slog.Error("could not read root certificates from file", "filename", filename, "error", err)
+			return nil, err
 		}
-		return netscaleradc.NewADnsProvider(environment, 10)
-	case ACME_CHALLENGE_PROVIDER_WEBSERVER:
-		return http01.NewProviderServer("", "12346"), nil
-	default:
-		return dns.NewDNSChallengeProviderByName(r.Challenge.Type)
 	}
+
+	if content, err = os.ReadFile(filename); err != nil {
+		slog.Error("could not read root certificates from file", "filename", filename, "error", err)
+		return nil, err
+	}
+
+	if !output.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("could not find root certificates in file %s", filename)
+	}
+	return output, nil
 }
 
 func (r Request) GetDomains() ([]string, error) {
 	return r.Content.GetDomains(r.basePath)
 }
 
+func (r Request) ListDomains() ([]string, error) {
+	return r.Content.ListDomains(r.basePath)
+}
+
 func (r Request) GetKeyType() certcrypto.KeyType {
 	switch r.KeyType {
 	case ACME_KEY_TYPE_EC256:
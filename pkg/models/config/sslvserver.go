@@ -16,7 +16,35 @@
 
 package config
 
+const (
+	SSL_VSERVER_TYPE_LB   = "lb"
+	SSL_VSERVER_TYPE_CS   = "cs"
+	SSL_VSERVER_TYPE_VPN  = "vpn"
+	SSL_VSERVER_TYPE_AAA  = "aaa"
+	SSL_VSERVER_TYPE_GSLB = "gslb"
+)
+
+// sslVirtualServer is an SSL virtual server to bind the certificate to
+// Type is optional, when set the virtual server is verified to exist as load balancing, content switching, gateway,
+// authentication or GSLB virtual server before binding the certificate
 type sslVirtualServer struct {
-	Name       string `json:"name" yaml:"name" mapstructure:"name"`
-	SniEnabled bool   `json:"sniEnabled" yaml:"sniEnabled" mapstructure:"sniEnabled"`
+	Name       string                       `json:"name" yaml:"name" mapstructure:"name"`
+	Type       string                       `json:"type" yaml:"type" mapstructure:"type"`
+	SniEnabled bool                         `json:"sniEnabled" yaml:"sniEnabled" mapstructure:"sniEnabled"`
+	Verify     sslVirtualServerVerification `json:"verify" yaml:"verify" mapstructure:"verify"`
+}
+
+// sslVirtualServerVerification configures the TLS handshake with the virtual server after the certificate is installed
+// Verification is disabled when no address is set
+type sslVirtualServerVerification struct {
+	Address string `json:"address" yaml:"address" mapstructure:"address"`
+	Port    int    `json:"port" yaml:"port" mapstructure:"port"`
+}
+
+// GetPort returns the port of the virtual server, defaulting to 443
+func (v sslVirtualServerVerification) GetPort() int {
+	if v.Port == 0 {
+		return 443
+	}
+	return v.Port
 }
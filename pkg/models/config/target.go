@@ -19,4 +19,5 @@ package config
 type Target struct {
 	Organization string `json:"organization" yaml:"organization" mapstructure:"organization"`
 	Environment  string `json:"environment" yaml:"environment" mapstructure:"environment"`
+	Partition    string `json:"partition" yaml:"partition" mapstructure:"partition"`
 }